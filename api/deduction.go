@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/andygello555/ginvoice/globals"
+	"strings"
+)
+
+// Deduction is an amount withheld From the total payable of an Invoice, such as IRPF in Spain or the ritenuta
+// d'acconto in Italy. It is either a percentage of the net total of the Invoice or a fixed amount.
+type Deduction struct {
+	// Name is the name of the Deduction (e.g. "IRPF"). This is optional.
+	Name   string
+	// Rate is the percentage of the net total that is withheld. This is only used if Amount is nil.
+	Rate   TaxRate
+	// Amount is the fixed amount that is withheld. It must be in the currency of the Invoice.
+	Amount *Money
+}
+
+// Withholding returns the amount withheld From an Invoice with the given net total, rounded using the given
+// RoundingMode.
+func (d *Deduction) Withholding(net *Money, mode RoundingMode) *Money {
+	if d.Amount != nil {
+		return d.Amount
+	}
+	return d.Rate.Tax(net, mode)
+}
+
+// String returns the Deduction in the same format that it is parsed From (e.g. "IRPF 15%" or "Retention EUR 50.00").
+func (d *Deduction) String() string {
+	amount := d.Rate.String()
+	if d.Amount != nil {
+		amount = d.Amount.StringAbbr()
+	}
+	if d.Name == "" {
+		return amount
+	}
+	return d.Name + " " + amount
+}
+
+// Set the Deduction From either a percentage or an amount of Money, which can be preceded by a name.
+//
+// A valid string value can be:
+//  IRPF 15%
+// Or:
+//  Retention EUR 50.00
+func (d *Deduction) Set(value string) error {
+	return d.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the Deduction like Set, but the amount is parsed using the Locale of the given ParserConfig.
+func (d *Deduction) SetWith(config *ParserConfig, value string) error {
+	words := strings.Fields(value)
+	if len(words) > 0 && strings.HasSuffix(words[len(words) - 1], "%") {
+		// The percentage can contain a space before the percent sign (e.g. "15 %")
+		rateStart := len(words) - 1
+		if words[rateStart] == "%" && rateStart > 0 {
+			rateStart--
+		}
+		deduction := Deduction{Name: strings.Join(words[:rateStart], " ")}
+		if err := deduction.Rate.Set(strings.Join(words[rateStart:], " ")); err != nil {
+			return errors.New(fmt.Sprintf("\"%s\" is not a valid deduction, deductions are given as a percentage of the net total (e.g. IRPF 15%%) or an amount (e.g. Retention EUR 50.00)", value))
+		}
+		*d = deduction
+		return nil
+	}
+
+	// Otherwise we find the shortest suffix that is an amount of Money, everything before it is the name
+	for start := len(words) - 1; start >= 0; start-- {
+		if amount, err := ParseMoneyLocale(strings.Join(words[start:], " "), config.locale()); err == nil {
+			if amount.IsNegative() {
+				return errors.New(fmt.Sprintf("\"%s\" is not a valid deduction, the amount cannot be negative", value))
+			}
+			*d = Deduction{Name: strings.Join(words[:start], " "), Amount: amount}
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("\"%s\" is not a valid deduction, deductions are given as a percentage of the net total (e.g. IRPF 15%%) or an amount (e.g. Retention EUR 50.00)", value))
+}
+
+// Deductions is a list of Deduction(s) that are withheld From the total payable of an Invoice.
+type Deductions []*Deduction
+
+func (ds *Deductions) String() string {
+	deductions := make([]string, len(*ds))
+	for n, deduction := range *ds {
+		deductions[n] = deduction.String()
+	}
+	return strings.Join(deductions, DefaultParserConfig.Separators.FirstLevel + " ")
+}
+
+// Set the Deductions From a comma-separated list of Deduction(s). Each Deduction can be quoted so that it can contain
+// a comma.
+//
+// A valid string value can be:
+//  IRPF 15%, Retention EUR 50.00
+func (ds *Deductions) Set(value string) error {
+	return ds.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the Deductions like Set, but Deduction(s) are separated by the first level separator of the given
+// ParserConfig and their amounts are parsed using its Locale.
+func (ds *Deductions) SetWith(config *ParserConfig, value string) error {
+	deductions := make(Deductions, 0)
+	for _, deductionStr := range config.Separators.FirstLevelSplit().Split(value, -1) {
+		deductionStr, err := globals.Unquote(deductionStr)
+		if err != nil {
+			return err
+		}
+		deduction := &Deduction{}
+		if err = deduction.SetWith(config, deductionStr); err != nil {
+			return err
+		}
+		deductions = append(deductions, deduction)
+	}
+	*ds = deductions
+	return nil
+}
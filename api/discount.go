@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Discount reduces an amount before tax is charged on it, either by a percentage or by a fixed amount. A Discount can
+// also be a surcharge, in which case the amount is increased instead.
+type Discount struct {
+	// Percent is the percentage that the amount is reduced by. This is only used if Amount is nil.
+	Percent   *big.Rat
+	// Amount is the fixed amount that the amount is reduced by.
+	Amount    *Money
+	// Surcharge is whether the amount is increased rather than reduced.
+	Surcharge bool
+}
+
+// IsZero returns whether the Discount does not change the amount it is applied To.
+func (d *Discount) IsZero() bool {
+	if d.Amount != nil {
+		return d.Amount.IsZero()
+	}
+	return d.Percent == nil || d.Percent.Sign() == 0
+}
+
+// Of returns the change To the given amount caused by the Discount, rounded using the given RoundingMode. This is
+// negative for discounts and positive for surcharges. Fixed Discount(s) are returned as is.
+func (d *Discount) Of(amount *Money, mode RoundingMode) *Money {
+	var change *Money
+	switch {
+	case d.Amount != nil:
+		change = d.Amount
+	case d.Percent != nil:
+		change = amount.MulRounded(new(big.Rat).Quo(d.Percent, big.NewRat(100, 1)), mode)
+	default:
+		change = &Money{0, amount.Currency}
+	}
+	if d.Surcharge {
+		return change
+	}
+	return change.Neg()
+}
+
+// allocate returns the change To each of the given amounts caused by the Discount. Percentage Discount(s) are applied
+// To each amount separately. Fixed Discount(s) are split between the positive amounts in proportion To their size
+// using Money.Allocate. An error is returned if a fixed Discount is larger than the total of the positive amounts, as
+// it would make the amounts negative.
+func (d *Discount) allocate(amounts []*Money, mode RoundingMode) ([]*Money, error) {
+	changes := make([]*Money, len(amounts))
+	if d.Amount == nil {
+		for n, amount := range amounts {
+			changes[n] = d.Of(amount, mode)
+		}
+		return changes, nil
+	}
+
+	ratios := make([]uint, len(amounts))
+	total := &Money{0, d.Amount.Currency}
+	for n, amount := range amounts {
+		if amount.Money > 0 {
+			ratios[n] = uint(amount.Money)
+			total = total.Add(amount)
+		}
+	}
+	if !d.Surcharge && total.Money > 0 && d.Amount.Money > total.Money {
+		return nil, errors.New(fmt.Sprintf("discount %s is larger than the total of %s that it is taken off", d.String(), total.StringAbbr()))
+	}
+	parts, err := d.Of(d.Amount, mode).Allocate(ratios...)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot apply %s To items that do not add up To more than zero", d.String()))
+	}
+	return parts, nil
+}
+
+// String returns the Discount as either a percentage (e.g. "10%") or an amount (e.g. "GBP 5.00").
+func (d *Discount) String() string {
+	if d.Amount != nil {
+		return d.Amount.StringAbbr()
+	}
+	percent := &TaxRate{Percent: d.Percent}
+	return percent.String()
+}
+
+// format returns the Discount in the same syntax that it is parsed From by SetWith using the given ParserConfig.
+func (d *Discount) format(config *ParserConfig) string {
+	if d.Amount != nil {
+		return config.locale().Abbr(d.Amount)
+	}
+	return d.String()
+}
+
+// Set the Discount From either a percentage or an amount of Money. Whether the Discount is a Surcharge is kept.
+//
+// A valid string value can be:
+//  10%
+// Or:
+//  GBP 5.00
+func (d *Discount) Set(value string) error {
+	return d.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the Discount like Set, but the amount is parsed using the Locale of the given ParserConfig.
+func (d *Discount) SetWith(config *ParserConfig, value string) error {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "%") {
+		percent, ok := new(big.Rat).SetString(strings.TrimSpace(strings.TrimSuffix(value, "%")))
+		if !ok || percent.Sign() < 0 || !d.Surcharge && percent.Cmp(big.NewRat(100, 1)) > 0 {
+			return errors.New(fmt.Sprintf("\"%s\" is not a valid discount, percentages must be between 0%% and 100%%", value))
+		}
+		*d = Discount{Percent: percent, Surcharge: d.Surcharge}
+		return nil
+	}
+
+	amount, err := ParseMoneyLocale(value, config.locale())
+	if err != nil {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid discount, discounts are given as a percentage (e.g. 10%%) or an amount (e.g. GBP 5.00): %s", value, err.Error()))
+	}
+	if amount.IsNegative() {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid discount, the amount cannot be negative", value))
+	}
+	*d = Discount{Amount: amount, Surcharge: d.Surcharge}
+	return nil
+}
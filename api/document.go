@@ -0,0 +1,413 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/andygello555/ginvoice/globals"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DocumentFormat is the format of a document that an Invoice can be decoded From.
+type DocumentFormat string
+
+const (
+	DocumentJSON DocumentFormat = "json"
+	DocumentYAML DocumentFormat = "yaml"
+	DocumentTOML DocumentFormat = "toml"
+)
+
+// tomlKeyRegex matches the first line of a TOML document that is not a comment or YAML document marker.
+var tomlKeyRegex = regexp.MustCompile(`^(?:\[.*\]|[A-Za-z0-9_-]+ *=.*)$`)
+
+// DocumentFormatFromPath returns the DocumentFormat of the file at the given path using its extension.
+func DocumentFormatFromPath(path string) (DocumentFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return DocumentJSON, nil
+	case ".yaml", ".yml":
+		return DocumentYAML, nil
+	case ".toml":
+		return DocumentTOML, nil
+	default:
+		return "", errors.New(fmt.Sprintf("cannot tell the format of \"%s\" From its extension, it must be one of .json, .yaml, .yml or .toml", path))
+	}
+}
+
+// DetectDocumentFormat guesses the DocumentFormat of the given document. Documents that start with a '{' are JSON,
+// documents whose first line is a TOML table or key-value pair are TOML, and everything else is YAML.
+func DetectDocumentFormat(data []byte) DocumentFormat {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return DocumentJSON
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		if tomlKeyRegex.MatchString(line) {
+			return DocumentTOML
+		}
+		break
+	}
+	return DocumentYAML
+}
+
+// Set the DocumentFormat From its name.
+//
+// A valid string value can be one of:
+//  json, yaml, yml, toml
+func (f *DocumentFormat) Set(value string) error {
+	switch format := DocumentFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case DocumentJSON, DocumentYAML, DocumentTOML:
+		*f = format
+	case "yml":
+		*f = DocumentYAML
+	default:
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid document format, it must be one of json, yaml or toml", value))
+	}
+	return nil
+}
+
+func (f *DocumentFormat) String() string {
+	return string(*f)
+}
+
+// DecodeInvoice decodes an Invoice From a JSON, YAML or TOML document. If the given DocumentFormat is empty then it is
+// detected using DetectDocumentFormat.
+//
+// The keys of the document are the same as the names of the command line flags, and each value can either be given in
+// the same format as its flag or in a structured form. For instance, the contacts and items can be tables of their
+// key-value pairs, and lists can be given instead of separated strings:
+//  number: 1
+//  from:
+//    firstName: John
+//    lastName: Smith
+//    address: [1 Smith Street, Smith Town, UK]
+//  to: "f: Jane, l: Doe, e: janedoe@example.com, p: 123123123, a: 1 Doe Street;UK"
+//  items:
+//    - description: Did thing
+//      hours: 1h30m
+//      rate: GBP 60.00
+//      tax: [GST 5%, QST 9.975% compound]
+//
+// The same validation takes place as when the values are given as command line flags. The locale, precision and
+// time-rounding keys are used while decoding the rest of the document, without changing the DefaultParserConfig. The
+// Invoice is not validated as a whole, so that fields can be overridden before it is given To NewInvoice.
+func DecodeInvoice(r io.Reader, format DocumentFormat) (*Invoice, error) {
+	return DecodeInvoiceWith(r, format, DefaultParserConfig)
+}
+
+// DecodeInvoiceWith decodes an Invoice like DecodeInvoice, but using the given ParserConfig instead of the
+// DefaultParserConfig. The given ParserConfig is not changed by the locale, precision and time-rounding keys.
+func DecodeInvoiceWith(r io.Reader, format DocumentFormat, config *ParserConfig) (*Invoice, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = DetectDocumentFormat(data)
+	}
+
+	document := make(map[string]interface{})
+	switch format {
+	case DocumentJSON:
+		err = json.Unmarshal(data, &document)
+	case DocumentYAML:
+		err = yaml.Unmarshal(data, &document)
+	case DocumentTOML:
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, errors.New(fmt.Sprintf("\"%s\" is not a valid document format, it must be one of json, yaml or toml", format))
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot decode %s invoice document: %s", strings.ToUpper(string(format)), err.Error()))
+	}
+
+	fields := make(map[string]interface{})
+	for key, value := range document {
+		fields[documentKey(key)] = value
+	}
+
+	// The locale, precision and time-rounding keys only change the copy of the ParserConfig used for this document
+	decoder := &documentDecoder{config: *config}
+	i := &Invoice{}
+	var discount, surcharge *Discount
+	for _, key := range documentKeys {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		delete(fields, key)
+		if err = decoder.decodeField(i, key, value, &discount, &surcharge); err != nil {
+			return nil, fmt.Errorf("invoice document \"%s\": %w", key, err)
+		}
+	}
+
+	if len(fields) > 0 {
+		unknown := make([]string, 0)
+		for key := range fields {
+			unknown = append(unknown, key)
+		}
+		sort.Strings(unknown)
+		return nil, errors.New(fmt.Sprintf("invoice document contains unknown keys: %s, the keys must be one of: %s", strings.Join(unknown, ", "), strings.Join(documentKeys, ", ")))
+	}
+	switch {
+	case discount != nil && surcharge != nil:
+		return nil, errors.New("invoice document can only contain one of \"discount\" and \"surcharge\"")
+	case discount != nil:
+		i.Discount = discount
+	case surcharge != nil:
+		i.Discount = surcharge
+	}
+	return i, nil
+}
+
+// documentKeys are the keys of an invoice document, in the order that they are decoded. The locale, precision and
+// time-rounding keys come first as they change how the rest of the document is parsed.
+var documentKeys = []string{
+	"locale", "precision", "timerounding", "number", "from", "to", "bank", "date", "due", "items", "inclusive",
+	"discount", "surcharge", "deductions", "terms", "rounding", "currencyrounding", "currency", "rates",
+}
+
+// documentKeyAliases maps the alternative names of keys in an invoice document To their names in documentKeys.
+var documentKeyAliases = map[string]string{
+	"invoicedate":  "date",
+	"duedate":      "due",
+	"taxinclusive": "inclusive",
+	"paymentterms": "terms",
+}
+
+// documentKey normalises the given key of an invoice document, or one of its tables, by making it lowercase and
+// removing any hyphens and underscores.
+func documentKey(key string) string {
+	key = strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(strings.TrimSpace(key)))
+	if alias, ok := documentKeyAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// documentDecoder holds the ParserConfig used To decode the values of an invoice document, which is changed by the
+// locale, precision and time-rounding keys.
+type documentDecoder struct {
+	config ParserConfig
+}
+
+// decodeField sets the field of the given Invoice with the given key of an invoice document To the given value. The
+// discount and surcharge are returned separately as only one of them can be given.
+func (d *documentDecoder) decodeField(i *Invoice, key string, value interface{}, discount, surcharge **Discount) (err error) {
+	config := &d.config
+	switch key {
+	case "from", "to":
+		contact := &Contact{}
+		if err = decodeKeyValueFlags(value, contact, contact.SetWith, config, config.Separators.SecondLevel); err == nil {
+			if key == "from" {
+				i.From = contact
+			} else {
+				i.To = contact
+			}
+		}
+	case "bank":
+		bank := &Bank{}
+		if err = decodeKeyValueFlags(value, bank, bank.SetWith, config, config.Separators.SecondLevel); err == nil {
+			i.Bank = bank
+		}
+	case "items":
+		i.Items, err = decodeItems(value, config)
+	case "number":
+		var number string
+		if number, err = documentValue(value, config, ""); err == nil {
+			var n uint64
+			if n, err = strconv.ParseUint(number, 10, 0); err != nil {
+				err = errors.New(fmt.Sprintf("\"%s\" is not a valid invoice number", number))
+			}
+			i.Number = uint(n)
+		}
+	case "precision":
+		var precision string
+		if precision, err = documentValue(value, config, ""); err == nil {
+			var n uint64
+			if n, err = strconv.ParseUint(precision, 10, 0); err != nil {
+				err = errors.New(fmt.Sprintf("\"%s\" is not a valid precision", precision))
+			}
+			places := uint(n)
+			config.Precision = &places
+		}
+	case "inclusive":
+		var inclusive string
+		if inclusive, err = documentValue(value, config, ""); err == nil {
+			i.TaxInclusive, err = strconv.ParseBool(inclusive)
+		}
+	case "locale":
+		i.Locale = &Locale{}
+		if err = decodeValue(value, i.Locale, config, config.Separators.FirstLevel); err == nil {
+			config.Locale = i.Locale
+		}
+	case "timerounding":
+		rounding := &TimeRounding{}
+		if err = decodeValue(value, rounding, config, config.Separators.FirstLevel); err == nil {
+			config.TimeRounding = rounding
+		}
+	case "date":
+		i.InvoiceDate = &Date{}
+		err = decodeValue(value, i.InvoiceDate, config, config.Separators.FirstLevel)
+	case "due":
+		i.DueDate = &Date{}
+		err = decodeValue(value, i.DueDate, config, config.Separators.FirstLevel)
+	case "discount":
+		*discount = &Discount{}
+		err = decodeValue(value, *discount, config, config.Separators.FirstLevel)
+	case "surcharge":
+		*surcharge = &Discount{Surcharge: true}
+		err = decodeValue(value, *surcharge, config, config.Separators.FirstLevel)
+	case "deductions":
+		err = decodeValue(value, &i.Deductions, config, config.Separators.FirstLevel)
+	case "terms":
+		i.Terms = &PaymentTerms{}
+		err = decodeValue(value, i.Terms, config, config.Separators.FirstLevel)
+	case "rounding":
+		err = decodeValue(value, &i.Rounding, config, config.Separators.FirstLevel)
+	case "currencyrounding":
+		err = decodeValue(value, &i.CurrencyRounding, config, config.Separators.FirstLevel)
+	case "currency":
+		err = decodeValue(value, &i.Currency, config, config.Separators.FirstLevel)
+	case "rates":
+		rates := &RateFile{}
+		if err = decodeValue(value, rates, config, config.Separators.FirstLevel); err == nil {
+			i.Rates = rates
+		}
+	}
+	return err
+}
+
+// decodeValue sets the given setter using the string form of the given value of an invoice document, using the given
+// ParserConfig if it is a configSetter. Lists are joined using the given separator.
+func decodeValue(value interface{}, s setter, config *ParserConfig, listSep string) error {
+	str, err := documentValue(value, config, listSep)
+	if err != nil {
+		return err
+	}
+	if cs, ok := s.(configSetter); ok {
+		return cs.SetWith(config, str)
+	}
+	return s.Set(str)
+}
+
+// decodeKeyValueFlags sets the given KeyValueFlags using the given ParserConfig From either a string, which is given To
+// set, or a table of key-value pairs. Both are set using setKeyVals, so that the same validation takes place. Lists
+// within the table are joined using the given separator.
+func decodeKeyValueFlags(value interface{}, t KeyValueFlags, set func(*ParserConfig, string) error, config *ParserConfig, listSep string) error {
+	if str, ok := value.(string); ok {
+		return set(config, str)
+	}
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return errors.New(fmt.Sprintf("expected a string or a table of key-value pairs, not %v", value))
+	}
+
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	keyVals := make([]string, len(keys))
+	for n, key := range keys {
+		str, err := documentValue(table[key], config, listSep)
+		if err != nil {
+			return errors.New(fmt.Sprintf("\"%s\": %s", key, err.Error()))
+		}
+		// Quotes and escapes in strings are kept as is, but separators can still be used To give a list
+		if _, ok := table[key].(string); ok {
+			str = globals.EscapeQuotes(str)
+		}
+		keyVals[n] = documentKey(key) + config.Separators.KeyValue + " " + str
+	}
+	return setKeyVals(keyVals, nil, t, config)
+}
+
+// decodeItems decodes the Items using the given ParserConfig From either a string, which is parsed using
+// Items.SetWith, or a list of items, each of which is either a string or a table of key-value pairs.
+func decodeItems(value interface{}, config *ParserConfig) (*Items, error) {
+	items := make(Items, 0)
+	if str, ok := value.(string); ok {
+		err := items.SetWith(config, str)
+		return &items, err
+	}
+
+	var list []interface{}
+	switch value.(type) {
+	case []interface{}:
+		list = value.([]interface{})
+	case []map[string]interface{}:
+		// TOML arrays of tables are decoded as a slice of maps
+		for _, table := range value.([]map[string]interface{}) {
+			list = append(list, table)
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("expected a string or a list of items, not %v", value))
+	}
+
+	for n, itemValue := range list {
+		item := &Item{}
+		set := func(config *ParserConfig, str string) error {
+			return setLogic(str, item, config, config.Separators.SecondLevelSplit())
+		}
+		if err := decodeKeyValueFlags(itemValue, item, set, config, config.Separators.ThirdLevel); err != nil {
+			return nil, fmt.Errorf("item %d: %w", n + 1, err)
+		}
+		items = append(items, item)
+	}
+	return &items, nil
+}
+
+// documentValue returns the string form of a scalar or list value of an invoice document. Lists are joined using the
+// given separator, and each element that contains one of the Separators of the given ParserConfig is quoted.
+func documentValue(value interface{}, config *ParserConfig, listSep string) (string, error) {
+	switch value.(type) {
+	case string:
+		return value.(string), nil
+	case bool:
+		return strconv.FormatBool(value.(bool)), nil
+	case float64:
+		f := value.(float64)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", errors.New(fmt.Sprintf("%v is not a valid number", f))
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(value.(int)), nil
+	case int64:
+		return strconv.FormatInt(value.(int64), 10), nil
+	case uint64:
+		return strconv.FormatUint(value.(uint64), 10), nil
+	case time.Time:
+		return value.(time.Time).Format("2/1/2006"), nil
+	case []interface{}:
+		if listSep == "" {
+			return "", errors.New(fmt.Sprintf("expected a single value, not the list %v", value))
+		}
+		values := make([]string, 0)
+		for _, element := range value.([]interface{}) {
+			str, err := documentValue(element, config, "")
+			if err != nil {
+				return "", err
+			}
+			values = append(values, config.Separators.Quote(str))
+		}
+		return strings.Join(values, listSep + " "), nil
+	default:
+		return "", errors.New(fmt.Sprintf("expected a single value or a list, not %v", value))
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDecodeInvoice(t *testing.T) {
+	from := Contact{
+		Company:   "John Smith",
+		FirstName: "John",
+		LastName:  "Smith",
+		Email:     "johnsmith@example.com",
+		PhoneNo:   "01234567890",
+		Address:   []string{"1 Smith Street", "Smith Town", "UK"},
+	}
+	date := Date(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC))
+	for _, test := range []struct{
+		document string
+		format   DocumentFormat
+		err      error
+		number   uint
+		from     *Contact
+		date     *Date
+		items    []string
+		// The Period of each item
+		periods  []Period
+	}{
+		{
+			document: `{
+				"number": 7,
+				"date": "2021-12-01",
+				"from": {"first": "John", "last": "Smith", "email": "johnsmith@example.com", "phone": "01234567890", "address": ["1 Smith Street", "Smith Town", "UK"]},
+				"items": [{"description": "Did thing", "hours": 7.5, "rate": "GBP 60.00", "tax": ["GST 5%", "QST 9.975% compound"]}, "d: Other thing; r: GBP 10"]
+			}`,
+			number:   7,
+			from:     &from,
+			date:     &date,
+			items:    []string{"Did thing", "Other thing"},
+		},
+		{
+			document: "number: 7\ndate: 2021-12-01\nfrom:\n  first-name: John\n  last_name: Smith\n  email: johnsmith@example.com\n  phone: \"01234567890\"\n  address: [1 Smith Street, Smith Town, UK]\nitems:\n  - description: Did thing\n    hours: 1h30m\n    rate: GBP 60.00\n",
+			number:   7,
+			from:     &from,
+			date:     &date,
+			items:    []string{"Did thing"},
+		},
+		{
+			document: "# An invoice\nnumber = 7\ndate = 2021-12-01\nfrom = \"f: John, l: Smith, e: johnsmith@example.com, p: 01234567890, a: 1 Smith Street;Smith Town;UK\"\n\n[[items]]\ndescription = \"Did thing\"\nrate = \"GBP 60.00\"\n\n[[items]]\ndescription = \"Other thing\"\nquantity = 2\nrate = \"GBP 10.00\"\n",
+			number:   7,
+			from:     &from,
+			date:     &date,
+			items:    []string{"Did thing", "Other thing"},
+		},
+		{
+			document: "items:\n  - description: Did thing\n    rate: GBP 60.00\n    period: 2021-12-01 to 2021-12-31\n  - description: Other thing\n    rate: GBP 10.00\n    period: 2021-12-01-2021-12-15\n  - \"d: Last thing; r: GBP 5; period: 1/12/2021 - 31/12/2021\"\n",
+			items:    []string{"Did thing", "Other thing", "Last thing"},
+			periods:  []Period{
+				{date, Date(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC))},
+				{date, Date(time.Date(2021, 12, 15, 0, 0, 0, 0, time.UTC))},
+				{date, Date(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC))},
+			},
+		},
+		{
+			document: "number: 7\n",
+			format:   DocumentJSON,
+			err:      errors.New("cannot decode JSON invoice document"),
+		},
+		{
+			document: "from:\n  first: John\n  last: Smith\n  email: not an email\n  phone: \"01234567890\"\n  address: [UK]\n",
+			err:      errors.New("invoice document \"from\": Contact details: invalid Email \"not an email\": \"not an email\" is not a valid email"),
+		},
+		{
+			document: "from:\n  first: John\n  last: Smith\n  email: johnsmith@example.com\n  phone: \"01234567890\"\n",
+			err:      errors.New("invoice document \"from\": Contact details: you need To give a key-value pair for each of the following required fields:\n\t- Address"),
+		},
+		{
+			document: "bank:\n  bank: Barclays\n  account: \"12345678\"\n  sort: 12-34-56\n",
+			err:      errors.New("invoice document \"bank\": Bank details: invalid SortCode \"12-34-56\": \"12-34-56\" is not a valid sort code"),
+		},
+		{
+			document: "items:\n  - description: Did thing\n    rate: GBP 60.00\n  - description: Other thing\n",
+			err:      errors.New("invoice document \"items\": item 2: "),
+		},
+		{
+			document: "items:\n  - description: Did thing\n    rate: GBP 60.00\n    discount: USD 5\n",
+			err:      errors.New("invoice document \"items\""),
+		},
+		{
+			document: "number: 7\ncustomer: Jane\n",
+			err:      errors.New("invoice document contains unknown keys: customer"),
+		},
+		{
+			document: "discount: 10%\nsurcharge: 5%\n",
+			err:      errors.New("invoice document can only contain one of \"discount\" and \"surcharge\""),
+		},
+		{
+			document: "number: [1, 2]\n",
+			err:      errors.New("invoice document \"number\": expected a single value"),
+		},
+	} {
+		invoice, err := DecodeInvoice(strings.NewReader(test.document), test.format)
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("decoding invoice document \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.document, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("decoding invoice document \"%s\" does not return the expected error: \"%s\"", test.document, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("decoding invoice document \"%s\" is not supposed To return error: \"%s\"", test.document, err.Error())
+		} else {
+			if invoice.Number != test.number {
+				t.Errorf("expected invoice number %d, got %d", test.number, invoice.Number)
+			}
+			if !reflect.DeepEqual(invoice.From, test.from) {
+				t.Errorf("expected from contact (%v) does not match actual from contact: %v", test.from, invoice.From)
+			}
+			if !reflect.DeepEqual(invoice.InvoiceDate, test.date) {
+				t.Errorf("expected invoice date (%v) does not match actual invoice date: %v", test.date, invoice.InvoiceDate)
+			}
+			descriptions := make([]string, 0)
+			if invoice.Items != nil {
+				for _, item := range *invoice.Items {
+					descriptions = append(descriptions, item.Description)
+				}
+			}
+			if !reflect.DeepEqual(descriptions, test.items) {
+				t.Errorf("expected items %v, got %v", test.items, descriptions)
+			}
+			for n, period := range test.periods {
+				if got := (*invoice.Items)[n].Period; got != period {
+					t.Errorf("expected item %d To have the period %s, got %s", n + 1, period.String(), got.String())
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeInvoiceWith(t *testing.T) {
+	locale, precision, rounding := *DefaultParserConfig.Locale, QuantityPrecision, *DefaultTimeRounding
+	config := &ParserConfig{Separators: DefaultParserConfig.Separators}
+	document := "locale: de-DE\nprecision: 2\ntime-rounding: 15m up\nitems:\n  - description: Did thing\n    hours: 1h1m\n    rate: 1.234,50 €\n"
+
+	// Documents are decoded concurrently To check that they do not share any state
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			invoice, err := DecodeInvoiceWith(strings.NewReader(document), DocumentYAML, config)
+			if err != nil {
+				t.Errorf("decoding invoice document returned an error: %s", err.Error())
+				return
+			}
+			if item := (*invoice.Items)[0]; item.HoursQuantity.String() != "1.25" || item.Rate.Money != 123450 {
+				t.Errorf("expected 1.25 hours at EUR 1234.50, got %s hours at %d", item.HoursQuantity.String(), item.Rate.Money)
+			}
+			if invoice.Locale == nil || invoice.Locale.Decimal != "," {
+				t.Errorf("expected the invoice To have the de-DE locale, got %v", invoice.Locale)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if config.Locale != nil || config.Precision != nil || config.TimeRounding != nil {
+		t.Errorf("decoding an invoice document changed the given ParserConfig")
+	}
+	if !reflect.DeepEqual(*DefaultParserConfig.Locale, locale) || QuantityPrecision != precision || *DefaultTimeRounding != rounding {
+		t.Errorf("decoding an invoice document changed the Locale of the DefaultParserConfig, QuantityPrecision or DefaultTimeRounding")
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ExchangeRate is the rate used To convert an amount From one Currency To another, as of the given Date.
+type ExchangeRate struct {
+	From Currency
+	To   Currency
+	// Rate is the amount of To that one major unit of From is worth.
+	Rate *big.Rat
+	Date Date
+}
+
+// Inverse returns the ExchangeRate that converts the other way around.
+func (er *ExchangeRate) Inverse() *ExchangeRate {
+	return &ExchangeRate{
+		From: er.To,
+		To:   er.From,
+		Rate: new(big.Rat).Inv(er.Rate),
+		Date: er.Date,
+	}
+}
+
+// Convert the given Money From the From Currency To the To Currency, rounding To the nearest minor unit of the To
+// Currency using the given RoundingMode.
+func (er *ExchangeRate) Convert(m *Money, mode RoundingMode) (*Money, error) {
+	if m.Currency != er.From {
+		return nil, errors.New(fmt.Sprintf("cannot convert %s using an exchange rate From %s", m.StringAbbr(), er.From.Abbr))
+	}
+	r := new(big.Rat).Mul(m.Rat(), er.Rate)
+	r.Mul(r, new(big.Rat).SetInt64(er.To.minorUnits()))
+	converted, err := roundMoney(r, mode, er.To)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot convert %s To %s: %s", m.StringAbbr(), er.To.Abbr, err.Error()))
+	}
+	return converted, nil
+}
+
+// String returns the ExchangeRate in the format:
+//  1 USD = 0.79 GBP (as of October 1st, 2021)
+// The Rate is written exactly, as it is given in the file of exchange rates, so that it is the same as the rate used
+// To convert amounts. The Inverse of a rate in the file is written the other way around (e.g. "1 EUR = 1.1325 USD" for
+// the rate From USD To EUR) and a Rate that cannot be written as a decimal either way is written as a fraction.
+func (er *ExchangeRate) String() string {
+	from, to, rate := er.From, er.To, er.Rate
+	places, ok := decimalPlaces(rate)
+	if !ok {
+		inverse := new(big.Rat).Inv(rate)
+		if inversePlaces, inverseOk := decimalPlaces(inverse); inverseOk {
+			from, to, rate, places, ok = er.To, er.From, inverse, inversePlaces, true
+		}
+	}
+	amount := rate.RatString()
+	if ok {
+		amount = rate.FloatString(places)
+	}
+	s := fmt.Sprintf("1 %s = %s %s", from.Abbr, amount, to.Abbr)
+	if !time.Time(er.Date).IsZero() {
+		s += fmt.Sprintf(" (as of %s)", er.Date.String())
+	}
+	return s
+}
+
+// RateProvider provides the ExchangeRate(s) used To convert items into the currency of an Invoice.
+type RateProvider interface {
+	// Rate returns the ExchangeRate From one Currency To another that is effective on the given Date.
+	Rate(from, to Currency, date Date) (*ExchangeRate, error)
+}
+
+// ExchangeRates is a table of ExchangeRate(s) that can be used To convert between currencies. ExchangeRates
+// implements RateProvider.
+type ExchangeRates []*ExchangeRate
+
+// effective finds the latest ExchangeRate From one Currency To another that is effective on the given Date (i.e. has
+// a Date on or before it). If only the rate going the other way around is within the table then its Inverse is
+// returned. ExchangeRate(s) without a Date are always effective, and if the given Date is zero then the latest
+// ExchangeRate is returned.
+func (ers ExchangeRates) effective(from, to Currency, date Date) *ExchangeRate {
+	var found *ExchangeRate
+	for _, rate := range ers {
+		if !time.Time(date).IsZero() && time.Time(rate.Date).After(time.Time(date)) {
+			continue
+		}
+		if found != nil && !time.Time(rate.Date).After(time.Time(found.Date)) {
+			continue
+		}
+		switch {
+		case rate.From == from && rate.To == to:
+			found = rate
+		case rate.From == to && rate.To == from:
+			found = rate.Inverse()
+		}
+	}
+	return found
+}
+
+// crossRateFigures is the number of significant figures that cross rates are rounded To.
+const crossRateFigures = 6
+
+// roundSignificant rounds the given positive rational To the given number of significant figures using RoundHalfUp.
+func roundSignificant(x *big.Rat, figures int) *big.Rat {
+	places := figures
+	if whole := new(big.Int).Quo(x.Num(), x.Denom()); whole.Sign() > 0 {
+		places -= len(whole.String())
+	} else {
+		// Each leading zero after the decimal point is not significant
+		for scaled := new(big.Rat).Mul(x, big.NewRat(10, 1)); scaled.Sign() > 0 && scaled.Cmp(big.NewRat(1, 1)) < 0; scaled.Mul(scaled, big.NewRat(10, 1)) {
+			places++
+		}
+	}
+	if places < 0 {
+		places = 0
+	}
+	return roundPlaces(x, uint(places))
+}
+
+// Rate finds the ExchangeRate From one Currency To another that is effective on the given Date. If there is no
+// ExchangeRate between the two currencies then a cross rate through a third Currency within the table is used (e.g.
+// USD To GBP through EUR for ECB reference rates), which is rounded To crossRateFigures significant figures. The Date
+// of a cross rate is the older of the two rates' Dates.
+func (ers ExchangeRates) Rate(from, to Currency, date Date) (*ExchangeRate, error) {
+	if from == to {
+		return &ExchangeRate{from, to, big.NewRat(1, 1), date}, nil
+	}
+	if rate := ers.effective(from, to, date); rate != nil {
+		return rate, nil
+	}
+
+	// Look for a cross rate
+	tried := make(map[Currency]struct{})
+	for _, candidate := range ers {
+		for _, via := range []Currency{candidate.From, candidate.To} {
+			if _, ok := tried[via]; ok || via == from || via == to {
+				continue
+			}
+			tried[via] = struct{}{}
+			first, second := ers.effective(from, via, date), ers.effective(via, to, date)
+			if first == nil || second == nil {
+				continue
+			}
+			crossDate := first.Date
+			if time.Time(second.Date).Before(time.Time(crossDate)) {
+				crossDate = second.Date
+			}
+			// Cross rates are rounded so that the rate printed on the invoice is the same as the rate used
+			return &ExchangeRate{from, to, roundSignificant(new(big.Rat).Mul(first.Rate, second.Rate), crossRateFigures), crossDate}, nil
+		}
+	}
+
+	if !time.Time(date).IsZero() {
+		return nil, errors.New(fmt.Sprintf("no exchange rate From %s To %s effective on %s", from.Abbr, to.Abbr, date.String()))
+	}
+	return nil, errors.New(fmt.Sprintf("no exchange rate From %s To %s", from.Abbr, to.Abbr))
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testECBRates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2021-12-10">
+			<Cube currency="USD" rate="1.1305"/>
+			<Cube currency="GBP" rate="0.85"/>
+			<Cube currency="CYP" rate="0.5"/>
+		</Cube>
+		<Cube time="2021-12-01">
+			<Cube currency="USD" rate="1.2"/>
+			<Cube currency="GBP" rate="0.8"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+	testCSVRates = `date,from,to,rate
+2021-12-01,USD,GBP,0.75
+1/12/2021,JPY,GBP,0.0065
+2021-12-08, USD, GBP, 0.76
+`
+)
+
+func TestReadRates(t *testing.T) {
+	date := func(day int) Date {
+		return Date(time.Date(2021, time.December, day, 0, 0, 0, 0, time.UTC))
+	}
+	for _, test := range []struct{
+		input string
+		err   error
+		from  Currency
+		to    Currency
+		date  Date
+		rate  *ExchangeRate
+	}{
+		{
+			input: testECBRates,
+			from:  Euro,
+			to:    UnitedStatesDollar,
+			date:  date(10),
+			rate:  &ExchangeRate{Euro, UnitedStatesDollar, big.NewRat(11305, 10000), date(10)},
+		},
+		{
+			input: testECBRates,
+			from:  GreatBritishPound,
+			to:    Euro,
+			date:  date(9),
+			rate:  &ExchangeRate{GreatBritishPound, Euro, big.NewRat(5, 4), date(1)},
+		},
+		{
+			input: testECBRates,
+			from:  UnitedStatesDollar,
+			to:    GreatBritishPound,
+			date:  date(5),
+			rate:  &ExchangeRate{UnitedStatesDollar, GreatBritishPound, big.NewRat(666667, 1000000), date(1)},
+		},
+		{
+			input: testECBRates,
+			from:  UnitedStatesDollar,
+			to:    GreatBritishPound,
+			date:  Date(time.Date(2021, time.November, 30, 0, 0, 0, 0, time.UTC)),
+			err:   errors.New("no exchange rate From USD To GBP effective on November 30th, 2021"),
+		},
+		{
+			input: testCSVRates,
+			from:  UnitedStatesDollar,
+			to:    GreatBritishPound,
+			date:  date(10),
+			rate:  &ExchangeRate{UnitedStatesDollar, GreatBritishPound, big.NewRat(76, 100), date(8)},
+		},
+		{
+			input: testCSVRates,
+			from:  UnitedStatesDollar,
+			to:    GreatBritishPound,
+			date:  date(7),
+			rate:  &ExchangeRate{UnitedStatesDollar, GreatBritishPound, big.NewRat(75, 100), date(1)},
+		},
+		{
+			input: testCSVRates,
+			from:  UnitedStatesDollar,
+			to:    JapaneseYen,
+			date:  date(1),
+			rate:  &ExchangeRate{UnitedStatesDollar, JapaneseYen, big.NewRat(115385, 1000), date(1)},
+		},
+		{
+			input: "2021-12-01,USD,ABC,0.75",
+			err:   errors.New("record 1: no currency with abbreviation: ABC"),
+		},
+		{
+			input: "2021-12-01,USD,GBP,-0.75",
+			err:   errors.New("record 1: \"-0.75\" is not a valid exchange rate"),
+		},
+		{
+			input: "12/31/2021,USD,GBP,0.75",
+			err:   errors.New("record 1: \"12/31/2021\" is not a valid date"),
+		},
+	} {
+		var rate *ExchangeRate
+		rates, err := ReadRates(strings.NewReader(test.input))
+		if err == nil {
+			rate, err = rates.Rate(test.from, test.to, test.date)
+		}
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("reading rates \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("reading rates \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("reading rates \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else if rate.From != test.rate.From || rate.To != test.rate.To || rate.Rate.Cmp(test.rate.Rate) != 0 || rate.Date != test.rate.Date {
+			t.Errorf("expected rate (%v) does not match actual rate: %v", test.rate, rate)
+		}
+	}
+}
+
+func TestExchangeRateConvert(t *testing.T) {
+	rate := &ExchangeRate{UnitedStatesDollar, JapaneseYen, big.NewRat(11345, 100), Date{}}
+	for _, test := range []struct{
+		in  Money
+		out Money
+	}{
+		{Money{1000, UnitedStatesDollar}, Money{1135, JapaneseYen}},
+		{Money{-1000, UnitedStatesDollar}, Money{-1135, JapaneseYen}},
+	} {
+		out, err := rate.Convert(&test.in, RoundHalfUp)
+		if err != nil {
+			t.Errorf("converting %v is not supposed To return error: \"%s\"", &test.in, err.Error())
+		} else if *out != test.out {
+			t.Errorf("converting %v should give %v, got: %v", &test.in, &test.out, out)
+		}
+	}
+	if _, err := rate.Convert(&Money{1000, Euro}, RoundHalfUp); err == nil {
+		t.Errorf("converting EUR using a USD exchange rate should return an error")
+	}
+	if _, err := rate.Convert(&Money{math.MaxInt64, UnitedStatesDollar}, RoundHalfUp); err == nil || !strings.Contains(err.Error(), "is too large To be represented as Money") {
+		t.Errorf("converting an amount that is too large To be represented as Money should return an error, got: %v", err)
+	}
+	// Rates are printed exactly, and inverse rates are printed the same way around as the rate they are the Inverse of
+	for _, rate := range []*ExchangeRate{rate, rate.Inverse(), rate.Inverse().Inverse()} {
+		if s := rate.String(); s != "1 USD = 113.45 JPY" {
+			t.Errorf("expected String() output (1 USD = 113.45 JPY) does not match actual output: %s", s)
+		}
+	}
+	if s := (&ExchangeRate{UnitedStatesDollar, JapaneseYen, big.NewRat(3, 7), Date{}}).String(); s != "1 USD = 3/7 JPY" {
+		t.Errorf("expected String() output (1 USD = 3/7 JPY) does not match actual output: %s", s)
+	}
+}
@@ -5,13 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/andygello555/gotils/ints"
-	str "github.com/andygello555/gotils/strings"
 	"github.com/johnfercher/maroto/pkg/color"
 	"github.com/johnfercher/maroto/pkg/consts"
 	"github.com/johnfercher/maroto/pkg/pdf"
 	"github.com/johnfercher/maroto/pkg/props"
 	"reflect"
-	"strconv"
 	"strings"
 )
 
@@ -23,10 +21,108 @@ type Invoice struct {
 	Bank        *Bank
 	InvoiceDate *Date
 	DueDate     *Date
+	// Rounding is the RoundingMode used when calculating the subtotal of each item. If this is RoundDefault then the
+	// RoundingMode set for each item's currency in CurrencyRounding is used.
+	Rounding    RoundingMode
+	// CurrencyRounding maps the abbreviation of a Currency To the RoundingMode used for amounts in that Currency when
+	// Rounding is RoundDefault. Currencies that are not in the map are rounded using RoundHalfUp.
+	CurrencyRounding CurrencyRoundings
+	// Currency is the Currency the invoice is issued in. If this is ZeroCurrency then all items must share the same
+	// currency, which is used instead. Otherwise, items in other currencies are converted using the ExchangeRate
+	// given by Rates that is effective on the InvoiceDate.
+	Currency    Currency
+	Rates       RateProvider
+	// Locale is the Locale used To format Money on the generated invoice. If this is nil then the DefaultLocale is
+	// used.
+	Locale      *Locale
+	// TaxInclusive is whether the Rate of every item already includes tax. Otherwise, only the items that are
+	// TaxInclusive themselves are treated as such.
+	TaxInclusive bool
+	// Deductions are withheld From the gross total of the Invoice To give the amount payable.
+	Deductions   Deductions
+	// Discount is applied To the items of the Invoice before tax is charged on them. It can also be a surcharge.
+	Discount     *Discount
+	// Terms are the PaymentTerms of the Invoice, which change the amount due depending on when it is paid.
+	Terms        *PaymentTerms
 }
 
-// NewInvoice constructs a new invoice From the given flags and checks if the required flags are not empty.
-func NewInvoice(number uint, from, to *Contact, items *Items, bank *Bank, invoiceDate, dueDate *Date) (*Invoice, error) {
+// InvoiceOption sets an optional field of an Invoice when it is constructed using NewInvoice.
+type InvoiceOption func(i *Invoice)
+
+// WithRounding sets the RoundingMode of the Invoice.
+func WithRounding(mode RoundingMode) InvoiceOption {
+	return func(i *Invoice) {
+		i.Rounding = mode
+	}
+}
+
+// WithCurrencyRounding sets the RoundingMode used for amounts in the given Currency when the Invoice has no Rounding.
+func WithCurrencyRounding(currency Currency, mode RoundingMode) InvoiceOption {
+	return func(i *Invoice) {
+		if i.CurrencyRounding == nil {
+			i.CurrencyRounding = make(CurrencyRoundings)
+		}
+		i.CurrencyRounding[currency.Abbr] = mode
+	}
+}
+
+// WithConversion sets the Currency of the Invoice. Items in other currencies will be converted into this Currency
+// using the ExchangeRate(s) given by the RateProvider.
+func WithConversion(currency Currency, rates RateProvider) InvoiceOption {
+	return func(i *Invoice) {
+		i.Currency = currency
+		i.Rates = rates
+	}
+}
+
+// WithLocale sets the Locale used To format Money on the generated invoice.
+func WithLocale(locale *Locale) InvoiceOption {
+	return func(i *Invoice) {
+		i.Locale = locale
+	}
+}
+
+// WithTaxInclusive treats the Rate of every item of the Invoice as tax-inclusive.
+func WithTaxInclusive() InvoiceOption {
+	return func(i *Invoice) {
+		i.TaxInclusive = true
+	}
+}
+
+// WithDeductions adds the given Deduction(s) To the Invoice.
+func WithDeductions(deductions ...*Deduction) InvoiceOption {
+	return func(i *Invoice) {
+		i.Deductions = append(i.Deductions, deductions...)
+	}
+}
+
+// WithDiscount sets the Discount (or surcharge) that is applied To the items of the Invoice before tax.
+func WithDiscount(discount *Discount) InvoiceOption {
+	return func(i *Invoice) {
+		i.Discount = discount
+	}
+}
+
+// WithPaymentTerms sets the PaymentTerms of the Invoice.
+func WithPaymentTerms(terms *PaymentTerms) InvoiceOption {
+	return func(i *Invoice) {
+		i.Terms = terms
+	}
+}
+
+// RequiredFieldsError is returned by NewInvoice, Validate and Generate when any of the required fields are nil or
+// empty. It contains the names of those fields.
+type RequiredFieldsError []string
+
+func (e RequiredFieldsError) Error() string {
+	return strings.Join(e, ", ")
+}
+
+// NewInvoice constructs a new invoice From the given flags and checks if the required flags are not empty. The given
+// options are then applied before the Invoice is validated.
+//
+// A RequiredFieldsError is returned if any required flags are empty, otherwise the error From Validate is returned.
+func NewInvoice(number uint, from, to *Contact, items *Items, bank *Bank, invoiceDate, dueDate *Date, options ...InvoiceOption) (*Invoice, error) {
 	i := Invoice{
 		Number:      number,
 		From:        from,
@@ -37,30 +133,532 @@ func NewInvoice(number uint, from, to *Contact, items *Items, bank *Bank, invoic
 		DueDate:     dueDate,
 	}
 
-	// Check required fields
+	if err := i.validateRequired(); err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		option(&i)
+	}
+	if err := i.Validate(); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// validateRequired checks that the required fields of the Invoice are not nil or empty. A RequiredFieldsError is
+// returned containing the names of the fields that are.
+func (i *Invoice) validateRequired() error {
 	needed := make([]string, 0)
-	addToNeeded := func(fieldName string) {
-		needed = append(needed, fieldName)
-	}
-	for _, reqField := range []string{"From", "To", "Items"} {
-		req := reflect.Indirect(reflect.ValueOf(i)).FieldByName(reqField).Interface()
-		switch req.(type) {
-		case *Contact:
-			contact := req.(*Contact)
-			if reflect.DeepEqual(*contact, Contact{}) {
-				addToNeeded(reqField)
+	for _, contact := range []struct{
+		name    string
+		contact *Contact
+	}{
+		{"From", i.From},
+		{"To", i.To},
+	} {
+		if contact.contact == nil || reflect.DeepEqual(*contact.contact, Contact{}) {
+			needed = append(needed, contact.name)
+		}
+	}
+	if i.Items == nil || len(*i.Items) == 0 {
+		needed = append(needed, "Items")
+	}
+	if i.InvoiceDate == nil {
+		needed = append(needed, "InvoiceDate")
+	}
+	if i.DueDate == nil {
+		needed = append(needed, "DueDate")
+	}
+	if len(needed) > 0 {
+		return RequiredFieldsError(needed)
+	}
+	return nil
+}
+
+// Validate checks that the required fields are given (see validateRequired), that the tax of each item is valid for
+// its TaxCategory (see validateTax), that the PaymentTerms agree with the dates of the Invoice (see validateTerms) and
+// that the items can be totalled in the currency of the Invoice, including any Discount (see validateCurrencies and
+// Discount.allocate).
+func (i *Invoice) Validate() error {
+	if err := i.validateRequired(); err != nil {
+		return err
+	}
+	if err := i.validateTax(); err != nil {
+		return err
+	}
+	if err := i.validateTerms(); err != nil {
+		return err
+	}
+	_, err := i.amounts()
+	return err
+}
+
+// currency returns the Currency that the Invoice is totalled in, which is the Currency shared by all of its items if it
+// has no Currency.
+func (i *Invoice) currency() (Currency, error) {
+	if i.Currency == ZeroCurrency {
+		return i.Items.Currency()
+	}
+	return i.Currency, nil
+}
+
+// validateCurrencies checks that the items of the Invoice can be totalled and that any fixed Discount, Deductions or
+// compensation are in the currency of the Invoice. If the Invoice has no Currency then all items must be in the same
+// currency, otherwise there must be an exchange rate between each item's currency and the Invoice's. Statutory
+// compensation can only be charged on Invoice(s) in GBP.
+func (i *Invoice) validateCurrencies() error {
+	currency, err := i.currency()
+	if err != nil {
+		return err
+	}
+	if i.Discount != nil && i.Discount.Amount != nil && i.Discount.Amount.Currency != currency {
+		return errors.New(fmt.Sprintf("discount \"%s\" is in %s but the invoice is in %s", i.Discount.String(), i.Discount.Amount.Currency.Abbr, currency.Abbr))
+	}
+	for _, deduction := range i.Deductions {
+		if deduction.Amount != nil && deduction.Amount.Currency != currency {
+			return errors.New(fmt.Sprintf("deduction \"%s\" is in %s but the invoice is in %s", deduction.String(), deduction.Amount.Currency.Abbr, currency.Abbr))
+		}
+	}
+	if i.Terms != nil {
+		if i.Terms.StatutoryCompensation && currency != GreatBritishPound {
+			return errors.New(fmt.Sprintf("statutory compensation can only be charged on invoices in GBP, not %s", currency.Abbr))
+		}
+		if !i.Terms.StatutoryCompensation && i.Terms.Compensation != nil && i.Terms.Compensation.Currency != currency {
+			return errors.New(fmt.Sprintf("compensation \"%s\" is in %s but the invoice is in %s", i.Terms.Compensation.StringAbbr(), i.Terms.Compensation.Currency.Abbr, currency.Abbr))
+		}
+	}
+	for _, item := range *i.Items {
+		itemCurrency := item.Currency()
+		if itemCurrency == currency {
+			continue
+		}
+		if i.Rates == nil {
+			return errors.New(fmt.Sprintf("cannot convert item \"%s\" To the invoice currency: no exchange rates given", item.Description))
+		}
+		if _, err := i.Rates.Rate(itemCurrency, currency, *i.InvoiceDate); err != nil {
+			return errors.New(fmt.Sprintf("cannot convert item \"%s\" To the invoice currency: %s", item.Description, err.Error()))
+		}
+	}
+	return nil
+}
+
+// validateTerms checks that the PaymentTerms of the Invoice agree with its InvoiceDate and DueDate.
+func (i *Invoice) validateTerms() error {
+	if i.Terms == nil {
+		return nil
+	}
+	days := i.InvoiceDate.DaysUntil(i.DueDate)
+	if i.Terms.NetDays > 0 && days != int(i.Terms.NetDays) {
+		return errors.New(fmt.Sprintf("the payment terms are net %d but the due date is %d days after the invoice date", i.Terms.NetDays, days))
+	}
+	if i.Terms.HasDiscount() && int(i.Terms.DiscountDays) > days {
+		return errors.New(fmt.Sprintf("the early payment discount lasts %d days but the due date is %d days after the invoice date", i.Terms.DiscountDays, days))
+	}
+	return nil
+}
+
+// validateTax checks that the TaxRate of each item is valid for its TaxCategory, that TaxExempt items have an
+// exemption reason, and that both contacts have a VAT ID if any items are TaxReverseCharge or TaxIntraCommunity.
+func (i *Invoice) validateTax() error {
+	for _, item := range *i.Items {
+		category := item.TaxCategory()
+		if _, ok := taxCategoryNames[category]; !ok {
+			return errors.New(fmt.Sprintf("item \"%s\" has an unknown tax category: %s", item.Description, category))
+		}
+		prefix := fmt.Sprintf("item \"%s\" has the tax category %s (%s)", item.Description, category.Description(), category.String())
+		switch {
+		case category.zeroRate() && !item.Tax.IsZero():
+			return errors.New(fmt.Sprintf("%s so cannot be taxed at %s", prefix, item.Tax.String()))
+		case !category.zeroRate() && item.Tax.IsZero():
+			return errors.New(prefix + " so must be taxed at a rate above 0%")
+		case category == TaxExempt && item.Exemption == "":
+			return errors.New(prefix + " so an exemption reason must be given")
+		case category == TaxReverseCharge || category == TaxIntraCommunity:
+			if i.From.VATID == "" {
+				return errors.New(prefix + " so the VAT ID of the From contact must be given")
 			}
-		case *Items:
-			is := req.(*Items)
-			if len(*is) == 0 {
-				addToNeeded(reqField)
+			if i.To.VATID == "" {
+				return errors.New(prefix + " so the VAT ID of the To contact must be given")
 			}
 		}
 	}
-	if len(needed) > 0 {
-		return nil, errors.New(strings.Join(needed, ", "))
+	return nil
+}
+
+// TaxLegends returns the wording that must be printed on the Invoice for the TaxCategory(s) of its items, in the order
+// that each TaxCategory is first used. Each exemption reason is given its own legend.
+func (i *Invoice) TaxLegends() []string {
+	legends := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, item := range *i.Items {
+		category := item.TaxCategory()
+		legend, ok := taxCategoryLegends[category]
+		if !ok {
+			continue
+		}
+		switch category {
+		case TaxExempt:
+			legend += ": " + item.Exemption
+		case TaxReverseCharge, TaxIntraCommunity:
+			if i.To != nil {
+				legend += ". Customer VAT ID: " + i.To.VATID
+			}
+		}
+		if _, ok = seen[legend]; !ok {
+			seen[legend] = struct{}{}
+			legends = append(legends, legend)
+		}
 	}
-	return &i, nil
+	return legends
+}
+
+// locale returns the Locale used To format Money on the generated invoice.
+func (i *Invoice) locale() *Locale {
+	if i.Locale == nil {
+		return DefaultLocale()
+	}
+	return i.Locale
+}
+
+// rounding returns the RoundingMode used for amounts in the given Currency.
+func (i *Invoice) rounding(currency Currency) RoundingMode {
+	if i.Rounding != RoundDefault {
+		return i.Rounding
+	}
+	return i.CurrencyRounding[currency.Abbr]
+}
+
+// itemAmounts is the net amount and tax of an item in the currency of the Invoice, along with the ExchangeRate used To
+// convert it if it had To be converted. The tax charged by each of the item's TaxComponent(s) and the change caused by
+// the Discount of the Invoice are also included, as is the base amount that the Discount of the Invoice was applied
+// To.
+type itemAmounts struct {
+	net      *Money
+	tax      *Money
+	taxes    []*Money
+	discount *Money
+	base     *Money
+	rate     *ExchangeRate
+}
+
+// taxInclusive returns whether the Rate of the given Item includes tax.
+func (i *Invoice) taxInclusive(item *Item) bool {
+	return i.TaxInclusive || item.TaxInclusive
+}
+
+// amounts returns the itemAmounts of each item of the Invoice in order.
+//
+// The net amount of each item, after its own Discount, is first converted into the currency of the Invoice. Then the
+// Discount of the Invoice is applied To each item (see Discount.allocate) so that tax is charged on the discounted
+// amount. The tax of tax-exclusive items is then calculated on their net amount. The net amounts of all the
+// tax-inclusive items that share a combined TaxRate are found together so that the TaxSummary follows the EN 16931
+// convention (see TaxRate.splitGross).
+func (i *Invoice) amounts() ([]*itemAmounts, error) {
+	// The Invoice may not have been validated (e.g. if it was decoded using DecodeInvoice), or its items may have been
+	// changed since, so it is checked again rather than panicking on missing fields or when Money in different
+	// currencies is added together
+	if err := i.validateRequired(); err != nil {
+		return nil, err
+	}
+	if err := i.validateCurrencies(); err != nil {
+		return nil, err
+	}
+	currency, _ := i.currency()
+	mode := i.rounding(currency)
+	amounts := make([]*itemAmounts, len(*i.Items))
+	bases := make([]*Money, len(*i.Items))
+	for n, item := range *i.Items {
+		// For tax-inclusive items this is the gross amount
+		net, _ := item.split(i.rounding(item.Currency()), false)
+		amount := &itemAmounts{net: net}
+		if i.Currency != ZeroCurrency && net.Currency != i.Currency {
+			var err error
+			if amount.rate, err = i.Rates.Rate(net.Currency, i.Currency, *i.InvoiceDate); err != nil {
+				return nil, err
+			}
+			if amount.net, err = amount.rate.Convert(net, mode); err != nil {
+				return nil, err
+			}
+		}
+		amount.base = amount.net
+		amounts[n] = amount
+		bases[n] = amount.net
+	}
+
+	// Apply the Discount of the Invoice
+	for n := range amounts {
+		amounts[n].discount = &Money{0, amounts[n].net.Currency}
+	}
+	if i.Discount != nil && !i.Discount.IsZero() {
+		changes, err := i.Discount.allocate(bases, mode)
+		if err != nil {
+			return nil, err
+		}
+		for n, change := range changes {
+			amounts[n].discount = change
+			amounts[n].net = amounts[n].net.Add(change)
+		}
+	}
+
+	inclusive := make(map[string][]int)
+	inclusiveOrder := make([]string, 0)
+	for n, item := range *i.Items {
+		amount := amounts[n]
+		if !i.taxInclusive(item) {
+			amount.taxes = item.Tax.Tax(amount.net, mode)
+			amount.tax = sumMoney(amount.net.Currency, amount.taxes...)
+			continue
+		}
+		rate := item.Tax.Rate()
+		key := rate.percent().RatString()
+		if _, ok := inclusive[key]; !ok {
+			inclusiveOrder = append(inclusiveOrder, key)
+		}
+		inclusive[key] = append(inclusive[key], n)
+	}
+
+	for _, key := range inclusiveOrder {
+		indices := inclusive[key]
+		grosses := make([]*Money, len(indices))
+		for n, index := range indices {
+			grosses[n] = amounts[index].net
+		}
+		taxRate := (*i.Items)[indices[0]].Tax.Rate()
+		for n, net := range taxRate.splitGross(grosses, mode) {
+			amount := amounts[indices[n]]
+			amount.net = net
+			amount.tax = grosses[n].Sub(net)
+			amount.taxes = (*i.Items)[indices[n]].Tax.split(net, amount.tax, mode)
+		}
+	}
+	return amounts, nil
+}
+
+// ItemAmounts returns the net amount and tax of the given Item in the currency of the Invoice. If the Item had To be
+// converted then the ExchangeRate used is also returned.
+func (i *Invoice) ItemAmounts(item *Item) (net *Money, tax *Money, rate *ExchangeRate, err error) {
+	amounts, err := i.amounts()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for n, other := range *i.Items {
+		if other == item {
+			return amounts[n].net, amounts[n].tax, amounts[n].rate, nil
+		}
+	}
+	return nil, nil, nil, errors.New(fmt.Sprintf("item \"%s\" is not on the invoice", item.Description))
+}
+
+// ItemSubtotal returns the subtotal of the given Item in the currency of the Invoice. If the Item had To be converted
+// then the ExchangeRate used is also returned.
+func (i *Invoice) ItemSubtotal(item *Item) (*Money, *ExchangeRate, error) {
+	net, tax, rate, err := i.ItemAmounts(item)
+	if err != nil {
+		return nil, nil, err
+	}
+	return net.Add(tax), rate, nil
+}
+
+// Total returns the sum of the subtotals of each item in the currency of the Invoice.
+func (i *Invoice) Total() (*Money, error) {
+	summary, err := i.TaxSummary()
+	if err != nil {
+		return nil, err
+	}
+	return summary.Gross, nil
+}
+
+// DiscountTotal returns the total change To the net amounts of the items caused by the Discount of the Invoice. This
+// is negative for discounts and positive for surcharges.
+func (i *Invoice) DiscountTotal() (*Money, error) {
+	amounts, err := i.amounts()
+	if err != nil {
+		return nil, err
+	}
+	total := &Money{0, i.Currency}
+	for _, amount := range amounts {
+		total = total.Add(amount.discount)
+	}
+	return total, nil
+}
+
+// NetTotal returns the sum of the net amounts of each item in the currency of the Invoice.
+func (i *Invoice) NetTotal() (*Money, error) {
+	summary, err := i.TaxSummary()
+	if err != nil {
+		return nil, err
+	}
+	return summary.Net, nil
+}
+
+// TaxTotal returns the sum of the tax of each item in the currency of the Invoice.
+func (i *Invoice) TaxTotal() (*Money, error) {
+	summary, err := i.TaxSummary()
+	if err != nil {
+		return nil, err
+	}
+	return summary.Tax, nil
+}
+
+// DeductionAmounts returns the amount withheld by each of the Deductions of the Invoice, in order. Percentage
+// Deduction(s) are calculated From the NetTotal.
+func (i *Invoice) DeductionAmounts() ([]*Money, error) {
+	net, err := i.NetTotal()
+	if err != nil {
+		return nil, err
+	}
+	amounts := make([]*Money, len(i.Deductions))
+	for n, deduction := range i.Deductions {
+		amounts[n] = deduction.Withholding(net, i.rounding(net.Currency))
+	}
+	return amounts, nil
+}
+
+// Withholding returns the total amount withheld by the Deductions of the Invoice.
+func (i *Invoice) Withholding() (*Money, error) {
+	net, err := i.NetTotal()
+	if err != nil {
+		return nil, err
+	}
+	amounts, err := i.DeductionAmounts()
+	if err != nil {
+		return nil, err
+	}
+	return sumMoney(net.Currency, amounts...), nil
+}
+
+// Payable returns the amount payable for the Invoice, which is the Total minus the Withholding.
+func (i *Invoice) Payable() (*Money, error) {
+	total, err := i.Total()
+	if err != nil {
+		return nil, err
+	}
+	withholding, err := i.Withholding()
+	if err != nil {
+		return nil, err
+	}
+	return total.Sub(withholding), nil
+}
+
+// AmountDue returns the amount due if the Invoice is paid on the given Date. This is the amount payable (see Payable)
+// minus the early payment discount if paid within the discount window of the PaymentTerms, or plus interest and
+// compensation if paid after the DueDate. Credit notes are not affected by the PaymentTerms.
+func (i *Invoice) AmountDue(on *Date) (*Money, error) {
+	payable, err := i.Payable()
+	if err != nil {
+		return nil, err
+	}
+	if i.Terms == nil || payable.Money <= 0 {
+		return payable, nil
+	}
+
+	switch daysLate := i.DueDate.DaysUntil(on); {
+	case i.Terms.HasDiscount() && i.InvoiceDate.DaysUntil(on) <= int(i.Terms.DiscountDays):
+		return payable.Sub(i.Terms.Discount(payable, i.rounding(payable.Currency))), nil
+	case daysLate > 0:
+		due := payable.Add(i.Terms.Interest(payable, daysLate, i.rounding(payable.Currency)))
+		if compensation := i.Terms.CompensationFor(payable); !compensation.IsZero() {
+			due = due.Add(compensation)
+		}
+		return due, nil
+	default:
+		return payable, nil
+	}
+}
+
+// TimeRoundings returns the description of each TimeRounding used To round the durations given as the HoursQuantity
+// of the items of the Invoice, in the order that they are first used.
+func (i *Invoice) TimeRoundings() []string {
+	roundings := make([]string, 0)
+	seen := make(map[TimeRounding]struct{})
+	for _, item := range *i.Items {
+		if rounding := item.HoursQuantity.Rounding; rounding != nil {
+			if _, ok := seen[*rounding]; !ok {
+				seen[*rounding] = struct{}{}
+				roundings = append(roundings, rounding.Describe())
+			}
+		}
+	}
+	return roundings
+}
+
+// PaymentTerms returns the PaymentTerms of the Invoice as human-readable sentences, with the dates and amounts that
+// they apply To.
+func (i *Invoice) PaymentTerms() ([]string, error) {
+	if i.Terms == nil || i.Terms.IsZero() {
+		return []string{}, nil
+	}
+	payable, err := i.Payable()
+	if err != nil {
+		return nil, err
+	}
+	locale := i.locale()
+
+	lines := make([]string, 0)
+	if i.Terms.HasDiscount() {
+		lastDay := i.InvoiceDate.AddDays(int(i.Terms.DiscountDays))
+		due, err := i.AmountDue(lastDay)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fmt.Sprintf("A %s discount is given if paid by %s, in which case %s is due.", ratPercent(i.Terms.DiscountPercent), lastDay.String(), locale.Format(due)))
+	}
+	if i.Terms.NetDays > 0 {
+		lines = append(lines, fmt.Sprintf("Payment is due within %d days, by %s.", i.Terms.NetDays, i.DueDate.String()))
+	}
+	if i.Terms.InterestRate != nil && i.Terms.InterestRate.Sign() != 0 {
+		daily := i.Terms.Interest(payable, 1, i.rounding(payable.Currency))
+		lines = append(lines, fmt.Sprintf("Interest is charged on late payments at %s per annum (%s per day).", ratPercent(i.Terms.InterestRate), locale.Format(daily)))
+	}
+	switch {
+	case i.Terms.StatutoryCompensation:
+		lines = append(lines, fmt.Sprintf("Fixed compensation of %s is charged on late payments under the Late Payment of Commercial Debts (Interest) Act 1998.", locale.Format(i.Terms.CompensationFor(payable))))
+	case i.Terms.Compensation != nil:
+		lines = append(lines, fmt.Sprintf("Fixed compensation of %s is charged on late payments.", locale.Format(i.Terms.Compensation)))
+	}
+	return lines, nil
+}
+
+// TaxSummary returns the tax analysis of the Invoice, which groups the net amount and tax of each item by TaxRate. All
+// amounts are in the currency of the Invoice.
+func (i *Invoice) TaxSummary() (*TaxSummary, error) {
+	summary := &TaxSummary{
+		Lines: make([]*TaxSummaryLine, 0),
+		Net:   &Money{0, i.Currency},
+		Tax:   &Money{0, i.Currency},
+		Gross: &Money{0, i.Currency},
+	}
+	amounts, err := i.amounts()
+	if err != nil {
+		return nil, err
+	}
+	for n, item := range *i.Items {
+		summary.add(item.TaxCategory(), item.Tax, amounts[n].net, amounts[n].tax, amounts[n].taxes)
+	}
+	return summary, nil
+}
+
+// UsedExchangeRates returns the ExchangeRate(s) that are used To convert items into the currency of the Invoice, in
+// the order that they are first used.
+func (i *Invoice) UsedExchangeRates() ExchangeRates {
+	used := make(ExchangeRates, 0)
+	seen := make(map[Currency]struct{})
+	amounts, err := i.amounts()
+	if err != nil {
+		return used
+	}
+	for _, amount := range amounts {
+		rate := amount.rate
+		if rate == nil {
+			continue
+		}
+		if _, ok := seen[rate.From]; !ok {
+			seen[rate.From] = struct{}{}
+			used = append(used, rate)
+		}
+	}
+	return used
 }
 
 func (i *Invoice) Generate() (bytes.Buffer, error) {
@@ -69,8 +667,49 @@ func (i *Invoice) Generate() (bytes.Buffer, error) {
 	grayColor := getGrayColor()
 	lightGrayColor := getLightGrayColor()
 	whiteColor := color.NewWhite()
-	header := getHeader()
-	contents := i.getContents()
+	// The Invoice may not have been created by NewInvoice, or may have been changed since, so it is validated again
+	if err := i.Validate(); err != nil {
+		return bytes.Buffer{}, err
+	}
+	summary, err := i.TaxSummary()
+	if err != nil {
+		return bytes.Buffer{}, err
+	}
+	total := summary.Gross
+	deductions, err := i.DeductionAmounts()
+	if err != nil {
+		return bytes.Buffer{}, err
+	}
+	payable, err := i.Payable()
+	if err != nil {
+		return bytes.Buffer{}, err
+	}
+	terms, err := i.PaymentTerms()
+	if err != nil {
+		return bytes.Buffer{}, err
+	}
+	usedRates := i.UsedExchangeRates()
+	locale := i.locale()
+	discounted := false
+	for _, item := range *i.Items {
+		discounted = discounted || !item.Discount.IsZero()
+	}
+	quantityHeader, _ := i.quantityHeader()
+	header, gridSizes := getHeader(quantityHeader, discounted, len(usedRates) > 0)
+	// If any items have been converted then we add a column for their subtotals in the invoice's currency
+	if len(usedRates) > 0 {
+		header[len(header) - 1] = "Subtotal (" + i.Currency.Abbr + ")"
+	}
+	contents, err := i.getContents(discounted, len(usedRates) > 0)
+	if err != nil {
+		return bytes.Buffer{}, err
+	}
+
+	// An invoice with a negative total is a credit note
+	title := "INVOICE "
+	if total.IsNegative() {
+		title = "CREDIT NOTE "
+	}
 
 	m := pdf.NewMaroto(consts.Portrait, consts.A4)
 	m.SetPageMargins(10, 15, 10)
@@ -89,7 +728,7 @@ func (i *Invoice) Generate() (bytes.Buffer, error) {
 		// INVOICE title
 		m.Row(10, func() {
 			m.Col(12, func() {
-				m.Text("INVOICE " + invoiceNumber, props.Text{
+				m.Text(title + invoiceNumber, props.Text{
 					Top:             3,
 					Style:           consts.Bold,
 					Size:            14,
@@ -198,10 +837,26 @@ func (i *Invoice) Generate() (bytes.Buffer, error) {
 				m.Text(i.To.PhoneNo, contactTextProps)
 			})
 		})
+
+		// VAT IDs
+		if i.From.VATID != "" || i.To.VATID != "" {
+			m.Row(5, func() {
+				for _, contact := range []*Contact{i.From, i.To} {
+					if contact.VATID != "" {
+						vatID := "VAT ID: " + contact.VATID
+						m.Col(4, func() {
+							m.Text(vatID, contactTextProps)
+						})
+					} else {
+						m.ColSpace(4)
+					}
+				}
+			})
+		}
 	})
 
 	// If bank details are given then add those in
-	if *i.Bank != (Bank{}) {
+	if i.Bank != nil && *i.Bank != (Bank{}) {
 		m.Row(5, emptyClosure)
 		quickString := func(s string) {
 			m.Row(5, func() {
@@ -270,24 +925,109 @@ func (i *Invoice) Generate() (bytes.Buffer, error) {
 
 	m.Row(7, emptyClosure)
 
-	// Item rundown
+	// Item rundown. The rows are only striped if there are no extra rows, as these would otherwise be striped separately
+	// From the items that they belong To
+	var alternatedBackground *color.Color
+	if !i.hasExtraRows() {
+		alternatedBackground = &grayColor
+	}
 	m.SetBackgroundColor(lightGrayColor)
 	m.TableList(header, contents, props.TableList{
 		HeaderProp: props.TableListContent{
 			Size:      10.5,
-			GridSizes: []uint{4, 2, 2, 2, 2},
+			GridSizes: gridSizes,
 		},
 		ContentProp: props.TableListContent{
 			Size:      11,
-			GridSizes: []uint{4, 2, 2, 2, 2},
+			GridSizes: gridSizes,
 		},
 		Align:                consts.Center,
-		AlternatedBackground: &grayColor,
+		AlternatedBackground: alternatedBackground,
 		HeaderContentSpace:   1,
 		Line:                 false,
 	})
 
-	// Total
+	footnote := func(s string, height float64) {
+		m.Row(height, func() {
+			m.Col(12, func() {
+				m.Text(s, props.Text{
+					Top:   2,
+					Size:  8,
+					Style: consts.Italic,
+					Align: consts.Left,
+					Color: darkGrayColor,
+				})
+			})
+		})
+	}
+
+	// The exchange rates used To convert items into the invoice's currency
+	if len(usedRates) > 0 {
+		m.Row(2, emptyClosure)
+		footnote("* Amounts converted into " + i.Currency.Abbr + " using the following exchange rates:", 5)
+		for _, rate := range usedRates {
+			footnote("    " + rate.String(), 5)
+		}
+	}
+
+	// The rounding of any items whose hours were given as durations
+	if roundings := i.TimeRoundings(); len(roundings) > 0 {
+		m.Row(2, emptyClosure)
+		for _, rounding := range roundings {
+			footnote(rounding, 5)
+		}
+	}
+
+	// Tax analysis, which is only needed if tax is charged at any rate other than 0% or any items are not zero rated
+	showTaxAnalysis := false
+	for _, line := range summary.Lines {
+		if !line.Rate.IsZero() || line.Category != TaxZeroRated {
+			showTaxAnalysis = true
+		}
+	}
+	if showTaxAnalysis {
+		taxGridSizes := []uint{4, 2, 2, 2, 2}
+		taxContents := make([][]string, 0)
+		for _, line := range summary.Lines {
+			category := fmt.Sprintf("%s (%s)", line.Category.Description(), line.Category.String())
+			taxContents = append(taxContents, []string{category, line.Rate.String(), locale.Format(line.Net), locale.Format(line.Tax), locale.Format(line.Gross)})
+		}
+		m.Row(7, emptyClosure)
+		m.SetBackgroundColor(lightGrayColor)
+		m.TableList([]string{"Tax Category", "Tax Rate", "Net", "Tax", "Gross"}, taxContents, props.TableList{
+			HeaderProp: props.TableListContent{
+				Size:      9,
+				GridSizes: taxGridSizes,
+			},
+			ContentProp: props.TableListContent{
+				Size:      9,
+				GridSizes: taxGridSizes,
+			},
+			Align:                consts.Center,
+			AlternatedBackground: &grayColor,
+			HeaderContentSpace:   1,
+			Line:                 false,
+		})
+	}
+
+	// The legal wording required for the tax categories of the items
+	if legends := i.TaxLegends(); len(legends) > 0 {
+		m.Row(2, emptyClosure)
+		for _, legend := range legends {
+			footnote(legend, 9)
+		}
+	}
+
+	// The payment terms, which are only shown for invoices that need To be paid
+	if !total.IsNegative() && len(terms) > 0 {
+		m.Row(2, emptyClosure)
+		footnote("Payment terms:", 5)
+		for _, term := range terms {
+			footnote("    " + term, 5)
+		}
+	}
+
+	// Subtotal, Tax and Total
 	m.RegisterFooter(func() {
 		m.Row(10, emptyClosure)
 		m.Row(10, func() {
@@ -303,52 +1043,255 @@ func (i *Invoice) Generate() (bytes.Buffer, error) {
 				})
 			})
 		})
-		m.Row(10, func() {
-			m.SetBackgroundColor(whiteColor)
-			m.ColSpace(8)
-			m.SetBackgroundColor(lightGrayColor)
-			m.Col(2, func() {
-				m.Text("Total: ", props.Text{
-					Top:   3,
-					Style: consts.Bold,
-					Size:  9,
-					Align: consts.Right,
+		summaryRow := func(name string, amount *Money, style consts.Style) {
+			m.Row(7, func() {
+				m.SetBackgroundColor(whiteColor)
+				m.ColSpace(8)
+				m.SetBackgroundColor(lightGrayColor)
+				m.Col(2, func() {
+					m.Text(name + ": ", props.Text{
+						Top:   2,
+						Style: style,
+						Size:  9,
+						Align: consts.Right,
+					})
 				})
-			})
-			m.Col(2, func() {
-				m.Text(i.Items.Total().StringAbbr(), props.Text{
-					Top:   3,
-					Style: consts.Bold,
-					Size:  9,
-					Align: consts.Left,
+				m.Col(2, func() {
+					m.Text(locale.Format(amount), props.Text{
+						Top:   2,
+						Style: style,
+						Size:  9,
+						Align: consts.Left,
+					})
 				})
 			})
-		})
+		}
+		summaryRow("Subtotal", summary.Net, consts.Normal)
+		// If any of the taxes are named then the total of each tax is shown instead
+		named := false
+		for _, item := range *i.Items {
+			named = named || item.Tax.Named()
+		}
+		if named {
+			for _, component := range summary.Components {
+				summaryRow(component.Component.String(), component.Tax, consts.Normal)
+			}
+		} else {
+			summaryRow("Tax", summary.Tax, consts.Normal)
+		}
+		summaryRow("Total", total, consts.Bold)
+
+		// Deductions are withheld From the total To give the amount payable
+		if len(i.Deductions) > 0 {
+			for n, deduction := range i.Deductions {
+				label := deduction.Name
+				switch {
+				case deduction.Amount == nil:
+					label = strings.TrimSpace(label + " " + deduction.Rate.String())
+				case label == "":
+					label = "Deduction"
+				}
+				summaryRow(label, deductions[n].Neg(), consts.Normal)
+			}
+			summaryRow("Amount payable", payable, consts.Bold)
+		}
 	})
 
 	buf, err := m.Output()
 	return buf, err
 }
 
-func getHeader() []string {
-	itemType := reflect.TypeOf(Item{})
-	headers := make([]string, 0)
-	for i := 0; i < itemType.NumField(); i++ {
-		headers = append(headers, str.JoinCamelcase(itemType.Field(i).Name, "/"))
+// quantityHeader returns the header of the quantity column of the item table. If all the items share the same Unit
+// then the header is named after it, otherwise the Unit of each item is shown next To its quantity, in which case
+// showUnits is true.
+func (i *Invoice) quantityHeader() (header string, showUnits bool) {
+	units := make(map[Unit]struct{})
+	var unit Unit
+	for _, item := range *i.Items {
+		unit = item.Unit
+		units[unit] = struct{}{}
+	}
+	switch {
+	case len(units) > 1:
+		return "Quantity", true
+	case unit == "":
+		return "Hours/Quantity", false
+	default:
+		return unit.Title(), false
 	}
-	headers = append(headers, "Subtotal")
-	return headers
 }
 
-func (i *Invoice) getContents() [][]string {
-	contents := make([][]string, 0)
+// hasExtraRows returns whether the item table contains any rows other than the items themselves and the Discount of
+// the Invoice. These are the sub-lines of items with details (see Item.Details) and the headings and subtotals of
+// ItemGroup(s).
+func (i *Invoice) hasExtraRows() bool {
 	for _, item := range *i.Items {
-		hrsQty := strconv.Itoa(int(item.HoursQuantity))
-		rate := fmt.Sprintf("%.2f", item.Rate.Float64())
-		tax := item.Tax.StringAbbr()
-		contents = append(contents, []string{item.Description, hrsQty, rate, tax, item.Subtotal().StringAbbr()})
+		if len(item.Details()) > 0 || item.Group != "" {
+			return true
+		}
 	}
-	return contents
+	return false
+}
+
+// getHeader returns the header of the item table along with the grid size of each column. The given quantity is the
+// header of the quantity column (see Invoice.quantityHeader). If discounted is true then a column is added for the
+// Discount of each item. If converted is true then a column is added for the subtotal of each item in the invoice's
+// currency.
+func getHeader(quantity string, discounted, converted bool) ([]string, []uint) {
+	switch {
+	case discounted && converted:
+		return []string{"Description", quantity, "Rate", "Discount", "Tax", "Subtotal", "Subtotal"}, []uint{2, 1, 2, 1, 2, 2, 2}
+	case discounted:
+		return []string{"Description", quantity, "Rate", "Discount", "Tax", "Subtotal"}, []uint{3, 1, 2, 2, 2, 2}
+	case converted:
+		return []string{"Description", quantity, "Rate", "Tax", "Subtotal", "Subtotal"}, []uint{3, 1, 2, 2, 2, 2}
+	default:
+		return []string{"Description", quantity, "Rate", "Tax", "Subtotal"}, []uint{4, 2, 2, 2, 2}
+	}
+}
+
+// getContents returns the rows of the item table. If discounted is true then each row also contains the Discount of
+// the item. If converted is true then each row also contains the subtotal of the item in the invoice's currency, which
+// is marked with an asterisk if the item was converted.
+//
+// Items that have details (see Item.Details) are followed by a sub-line which only contains the details in the
+// description column. If any items have a Group then the items are shown under the heading of their ItemGroup, which
+// is followed by a row containing the subtotal of the ItemGroup.
+//
+// The subtotals of the items are shown before the Discount of the Invoice, which has its own row at the end of the
+// table followed by a row for the change in tax caused by the Discount. The last column of the table therefore adds up
+// To the total of the Invoice.
+func (i *Invoice) getContents(discounted, converted bool) ([][]string, error) {
+	locale := i.locale()
+	amounts, err := i.amounts()
+	if err != nil {
+		return nil, err
+	}
+	_, showUnits := i.quantityHeader()
+	columns := 5
+	if discounted {
+		columns++
+	}
+	if converted {
+		columns++
+	}
+	index := make(map[*Item]int)
+	for n, item := range *i.Items {
+		index[item] = n
+	}
+
+	// Items are only shown under headings if any of them have a Group
+	groups := i.Items.Groups()
+	grouped := len(groups) > 1 || groups[0].Name != ""
+	contents := make([][]string, 0)
+	// shown is the sum of the last column of every item
+	var shown *Money
+	for _, group := range groups {
+		name := group.Name
+		if name == "" {
+			name = "Other"
+		}
+		if grouped {
+			heading := make([]string, columns)
+			heading[0] = name
+			contents = append(contents, heading)
+		}
+
+		// The subtotal of the group is the sum of the last column of each of its items
+		var groupTotal *Money
+		for _, item := range group.Items {
+			n := index[item]
+			hrsQty := locale.Quantity(&item.HoursQuantity)
+			if showUnits && item.Unit != "" {
+				hrsQty += " " + item.Unit.Name(&item.HoursQuantity)
+			}
+			rate := locale.Amount(&item.Rate)
+			tax := item.Tax.Rates()
+			if i.taxInclusive(item) {
+				tax += " incl."
+			}
+			if item.Category != "" {
+				tax += " (" + item.Category.String() + ")"
+			}
+			row := []string{item.Description, hrsQty, rate}
+			if discounted {
+				discount := ""
+				if !item.Discount.IsZero() {
+					discount = item.Discount.String()
+				}
+				row = append(row, discount)
+			}
+			row = append(row, tax)
+
+			// The last column is the base amount of the item in the currency of the Invoice before the Discount of the
+			// Invoice, plus the tax on it. The base amount is the gross amount of tax-inclusive items.
+			subtotal := amounts[n].base
+			if !i.taxInclusive(item) {
+				subtotal = subtotal.Add(sumMoney(subtotal.Currency, item.Tax.Tax(subtotal, i.rounding(subtotal.Currency))...))
+			}
+			if converted {
+				net, itemTax := item.split(i.rounding(item.Currency()), i.taxInclusive(item))
+				convertedStr := locale.Format(subtotal)
+				if amounts[n].rate != nil {
+					convertedStr += "*"
+				}
+				row = append(row, locale.Format(net.Add(itemTax)), convertedStr)
+			} else {
+				row = append(row, locale.Format(subtotal))
+			}
+			contents = append(contents, row)
+			if groupTotal == nil {
+				groupTotal = subtotal
+			} else {
+				groupTotal = groupTotal.Add(subtotal)
+			}
+			if shown == nil {
+				shown = subtotal
+			} else {
+				shown = shown.Add(subtotal)
+			}
+
+			// The details of the item are shown on a sub-line under its description
+			if details := item.Details(); len(details) > 0 {
+				subLine := make([]string, columns)
+				subLine[0] = strings.Join(details, ", ")
+				contents = append(contents, subLine)
+			}
+		}
+
+		if grouped {
+			subtotalRow := make([]string, columns)
+			subtotalRow[0] = name + " subtotal"
+			subtotalRow[columns - 1] = locale.Format(groupTotal)
+			contents = append(contents, subtotalRow)
+		}
+	}
+
+	// The Discount of the Invoice is taken off before tax so its row shows the change To the net total. The change To
+	// the tax that this causes is shown on its own row so that the table adds up To the total of the Invoice.
+	if i.Discount != nil && !i.Discount.IsZero() {
+		total, discount := &Money{0, shown.Currency}, &Money{0, shown.Currency}
+		for _, amount := range amounts {
+			total = total.Add(amount.net).Add(amount.tax)
+			discount = discount.Add(amount.discount)
+		}
+		label := "Discount "
+		if i.Discount.Surcharge {
+			label = "Surcharge "
+		}
+		row := make([]string, columns)
+		row[0] = label + i.Discount.String() + " (before tax)"
+		row[len(row) - 1] = locale.Format(discount)
+		contents = append(contents, row)
+
+		if taxChange := total.Sub(shown).Sub(discount); taxChange.Money != 0 {
+			row = make([]string, columns)
+			row[0] = "Tax on " + strings.ToLower(label) + i.Discount.String()
+			row[len(row) - 1] = locale.Format(taxChange)
+			contents = append(contents, row)
+		}
+	}
+	return contents, nil
 }
 
 func getDarkGrayColor() color.Color {
@@ -384,5 +1327,18 @@ To contact: %v
 Invoice date: %v
 Due date: %v
 
-Items: %v`, i.Number, i.From, i.To, i.InvoiceDate, i.DueDate, i.Items)
+Items: %v`, i.Number, i.From, i.To, i.InvoiceDate, i.DueDate, i.itemsString())
+}
+
+// itemsString returns the Items of the Invoice like Items.String, but with each subtotal rounded using the
+// RoundingMode of the Invoice for the item's currency.
+func (i *Invoice) itemsString() string {
+	if i.Items == nil {
+		return "<nil>"
+	}
+	var b strings.Builder
+	for n, item := range *i.Items {
+		b.WriteString(fmt.Sprintf("Item %d: %s", n + 1, item.describe(item.SubtotalRounded(i.rounding(item.Currency())))))
+	}
+	return b.String()
 }
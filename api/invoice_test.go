@@ -0,0 +1,993 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// testContact returns a Contact that can be used as both the From and To contacts of a test Invoice.
+func testContact() *Contact {
+	return &Contact{
+		Company:   "Company",
+		FirstName: "John",
+		LastName:  "Smith",
+		Email:     "johnsmith@example.com",
+		PhoneNo:   "123123123",
+		Address:   []string{"1 Smith Street", "UK"},
+	}
+}
+
+// testInvoice constructs an Invoice using NewInvoice with the given items and options.
+func testInvoice(itemsStr string, options ...InvoiceOption) (*Invoice, error) {
+	items := make(Items, 0)
+	if err := items.Set(itemsStr); err != nil {
+		return nil, err
+	}
+	date := Date(time.Date(2021, time.December, 10, 0, 0, 0, 0, time.UTC))
+	return NewInvoice(1, testContact(), testContact(), &items, &Bank{}, &date, &date, options...)
+}
+
+func TestInvoiceCurrency(t *testing.T) {
+	rateDate := Date(time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC))
+	rates := ExchangeRates{
+		{UnitedStatesDollar, GreatBritishPound, big.NewRat(3, 4), rateDate},
+		{GreatBritishPound, Euro, big.NewRat(6, 5), rateDate},
+	}
+	for _, test := range []struct{
+		input   string
+		options []InvoiceOption
+		err     error
+		total   Money
+		rates   int
+	}{
+		{
+			input: "d:Did thing 1;r:GBP 10,d:Did thing 2;h:2;r:GBP 5",
+			total: Money{2000, GreatBritishPound},
+		},
+		{
+			input: "d:Did thing 1;r:GBP 10,d:Did thing 2;r:USD 10",
+			err:   errors.New("items have mixed currencies: item 1 is in GBP but item 2 is in USD"),
+		},
+		{
+			input: "d:Did thing 1;r:GBP 10;t:USD 1",
+			err:   errors.New("\"USD 1\" is not a valid tax rate"),
+		},
+		{
+			input:   "d:Did thing 1;r:GBP 10,d:Did thing 2;r:USD 10",
+			options: []InvoiceOption{WithConversion(GreatBritishPound, rates)},
+			total:   Money{1750, GreatBritishPound},
+			rates:   1,
+		},
+		{
+			input:   "d:Did thing 1;r:GBP 10,d:Did thing 2;r:EUR 12",
+			options: []InvoiceOption{WithConversion(GreatBritishPound, rates)},
+			total:   Money{2000, GreatBritishPound},
+			rates:   1,
+		},
+		{
+			input:   "d:Did thing 1;r:GBP 10,d:Did thing 2;r:JPY 1000",
+			options: []InvoiceOption{WithConversion(GreatBritishPound, rates)},
+			err:     errors.New("cannot convert item \"Did thing 2\" To the invoice currency: no exchange rate From JPY To GBP"),
+		},
+	} {
+		invoice, err := testInvoice(test.input, test.options...)
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("creating invoice with items \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("creating invoice with items \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("creating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else {
+			total, err := invoice.Total()
+			if err != nil {
+				t.Errorf("totalling invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			} else if *total != test.total {
+				t.Errorf("invoice with items \"%s\" does not have the expected total of %v, instead it is: %v", test.input, &test.total, total)
+			}
+			if used := invoice.UsedExchangeRates(); len(used) != test.rates {
+				t.Errorf("invoice with items \"%s\" should use %d exchange rates, instead it uses: %v", test.input, test.rates, used)
+			}
+		}
+	}
+
+	// Required fields are reported using a RequiredFieldsError
+	items := make(Items, 0)
+	_, err := NewInvoice(1, &Contact{}, testContact(), &items, &Bank{}, &Date{}, &Date{})
+	var requiredErr RequiredFieldsError
+	if !errors.As(err, &requiredErr) || requiredErr.Error() != "From, Items" {
+		t.Errorf("creating an invoice without From and Items should return a RequiredFieldsError, got: %v", err)
+	}
+
+	// Items in a different currency that are added after the invoice is created return an error rather than panicking
+	invoice, err := testInvoice("d:Did thing 1;r:GBP 10")
+	if err != nil {
+		t.Fatalf("creating invoice is not supposed To return error: \"%s\"", err.Error())
+	}
+	*invoice.Items = append(*invoice.Items, &Item{Description: "Did thing 2", HoursQuantity: NewQuantity(1), Rate: Money{1000, UnitedStatesDollar}})
+	if total, err := invoice.Total(); err == nil || !strings.Contains(err.Error(), "items have mixed currencies") {
+		t.Errorf("totalling an invoice with items in GBP and USD should return a mixed currencies error, got: %v, %v", total, err)
+	}
+}
+
+func TestInvoiceTaxSummary(t *testing.T) {
+	invoice, err := testInvoice("d:Thing 1;h:3;r:GBP 10;t:standard,d:Thing 2;r:GBP 5.55;t:reduced,d:Thing 3;r:GBP 2.50;t:20%,d:Book;r:GBP 7.99")
+	if err != nil {
+		t.Fatalf("creating invoice is not supposed To return error: \"%s\"", err.Error())
+	}
+	summary, err := invoice.TaxSummary()
+	if err != nil {
+		t.Fatalf("summarising the tax of the invoice is not supposed To return error: \"%s\"", err.Error())
+	}
+
+	for n, expected := range []struct{
+		rate  string
+		net   Money
+		tax   Money
+		gross Money
+	}{
+		{"20%", Money{3250, GreatBritishPound}, Money{650, GreatBritishPound}, Money{3900, GreatBritishPound}},
+		{"5%", Money{555, GreatBritishPound}, Money{28, GreatBritishPound}, Money{583, GreatBritishPound}},
+		{"0%", Money{799, GreatBritishPound}, Money{0, GreatBritishPound}, Money{799, GreatBritishPound}},
+	} {
+		if n >= len(summary.Lines) {
+			t.Errorf("tax summary should have a line for %s", expected.rate)
+			continue
+		}
+		line := summary.Lines[n]
+		if line.Rate.String() != expected.rate || *line.Net != expected.net || *line.Tax != expected.tax || *line.Gross != expected.gross {
+			t.Errorf("tax summary line %d should be %s (net: %v, tax: %v, gross: %v), got: %s (net: %v, tax: %v, gross: %v)", n + 1, expected.rate, &expected.net, &expected.tax, &expected.gross, line.Rate.String(), line.Net, line.Tax, line.Gross)
+		}
+	}
+	if len(summary.Lines) != 3 {
+		t.Errorf("tax summary should have 3 lines, got: %d", len(summary.Lines))
+	}
+
+	if *summary.Net != (Money{4604, GreatBritishPound}) || *summary.Tax != (Money{678, GreatBritishPound}) || *summary.Gross != (Money{5282, GreatBritishPound}) {
+		t.Errorf("tax summary totals are incorrect (net: %v, tax: %v, gross: %v)", summary.Net, summary.Tax, summary.Gross)
+	}
+	if total, _ := invoice.Total(); *total != *summary.Gross {
+		t.Errorf("invoice total (%v) should equal the gross total of the tax summary: %v", total, summary.Gross)
+	}
+}
+
+func TestInvoiceTaxInclusive(t *testing.T) {
+	for _, test := range []struct{
+		input   string
+		options []InvoiceOption
+		net     Money
+		tax     Money
+		gross   Money
+	}{
+		{
+			input: "d:Thing 1;r:GBP 12;t:20%;incl:true",
+			net:   Money{1000, GreatBritishPound},
+			tax:   Money{200, GreatBritishPound},
+			gross: Money{1200, GreatBritishPound},
+		},
+		{
+			// Each line would be 0.83 net and 0.17 tax, which would give 2.49 net and 0.51 tax. Instead the tax is
+			// calculated on the total net of 2.50.
+			input: "d:Thing 1;r:GBP 1;t:20%,d:Thing 2;r:GBP 1;t:20%,d:Thing 3;r:GBP 1;t:20%",
+			options: []InvoiceOption{WithTaxInclusive()},
+			net:   Money{250, GreatBritishPound},
+			tax:   Money{50, GreatBritishPound},
+			gross: Money{300, GreatBritishPound},
+		},
+		{
+			input: "d:Thing 1;r:GBP 10;t:20%,d:Thing 2;r:GBP 12;t:20%;gross:true",
+			net:   Money{2000, GreatBritishPound},
+			tax:   Money{400, GreatBritishPound},
+			gross: Money{2400, GreatBritishPound},
+		},
+	} {
+		invoice, err := testInvoice(test.input, test.options...)
+		if err != nil {
+			t.Errorf("creating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		summary, err := invoice.TaxSummary()
+		if err != nil {
+			t.Errorf("summarising the tax of items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else if *summary.Net != test.net || *summary.Tax != test.tax || *summary.Gross != test.gross {
+			t.Errorf("tax summary of items \"%s\" should be (net: %v, tax: %v, gross: %v), got: (net: %v, tax: %v, gross: %v)", test.input, &test.net, &test.tax, &test.gross, summary.Net, summary.Tax, summary.Gross)
+		}
+	}
+}
+
+func TestInvoiceTaxInclusiveProperty(t *testing.T) {
+	// The tax summary of tax-inclusive items should always reconcile with the sum of the gross amounts, and the net
+	// amounts of the items should always add up To the net total of the summary
+	if err := quick.Check(func(grosses []int16, percent uint8) bool {
+		if len(grosses) == 0 {
+			return true
+		}
+		items := make(Items, 0)
+		gross := &Money{0, GreatBritishPound}
+		for _, g := range grosses {
+			item := &Item{
+				Description:   "item",
+				HoursQuantity: NewQuantity(1),
+				Rate:          Money{int64(g), GreatBritishPound},
+				Tax:           Taxes{{Rate: TaxRate{Percent: big.NewRat(int64(percent % 30), 1)}}},
+			}
+			items = append(items, item)
+			gross = gross.Add(&item.Rate)
+		}
+		date := Date(time.Date(2021, time.December, 10, 0, 0, 0, 0, time.UTC))
+		invoice, err := NewInvoice(1, testContact(), testContact(), &items, &Bank{}, &date, &date, WithTaxInclusive())
+		if err != nil {
+			return false
+		}
+		summary, err := invoice.TaxSummary()
+		if err != nil || *summary.Gross != *gross || *summary.Net.Add(summary.Tax) != *gross {
+			return false
+		}
+		net := &Money{0, GreatBritishPound}
+		for _, item := range items {
+			itemNet, _, _, err := invoice.ItemAmounts(item)
+			if err != nil {
+				return false
+			}
+			net = net.Add(itemNet)
+		}
+		return *net == *summary.Net
+	}, nil); err != nil {
+		t.Errorf("tax summary of tax-inclusive items does not reconcile: %v", err)
+	}
+}
+
+func TestInvoiceGenerate(t *testing.T) {
+	for _, test := range []struct{
+		input   string
+		options []InvoiceOption
+	}{
+		{input: "d:Thing 1;r:GBP 10"},
+		{input: "d:Thing 1;h:3;r:GBP 12;t:standard,d:Thing 2;r:GBP 5;t:reduced;incl:true"},
+		{input: "d:Thing 1;r:GBP 12;t:standard", options: []InvoiceOption{WithTaxInclusive()}},
+		{input: "d:Thing 1;h:7.5;u:hours;r:GBP 12,d:Thing 2;h:0.25;u:kg;r:GBP 5"},
+		{input: "d:Thing 1;h:1h20m;r:GBP 60,d:Thing 2;h:00:07;r:GBP 60"},
+		{input: "d:Thing 1;r:GBP 60;group:Discovery,d:Thing 2;r:GBP 5;group:Build;sku:ABC-123,d:Thing 3;r:GBP 5"},
+		{input: "d:Thing 1;r:GBP 60;sku:ABC-123;po:4500012/10;period:1/12/2021 to 31/12/2021,d:Thing 2;r:GBP 5;note:Delivered on site"},
+		{
+			input:   "d:Thing 1;r:GBP 12;disc:10%;t:standard,d:Thing 2;r:USD 10",
+			options: []InvoiceOption{
+				WithConversion(GreatBritishPound, ExchangeRates{{UnitedStatesDollar, GreatBritishPound, big.NewRat(3, 4), Date(time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC))}}),
+				WithDiscount(&Discount{Amount: &Money{100, GreatBritishPound}}),
+			},
+		},
+	} {
+		invoice, err := testInvoice(test.input, test.options...)
+		if err != nil {
+			t.Errorf("creating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		if buf, err := invoice.Generate(); err != nil {
+			t.Errorf("generating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+			t.Errorf("generating invoice with items \"%s\" does not output a PDF", test.input)
+		}
+	}
+}
+
+func TestInvoiceTaxCategory(t *testing.T) {
+	date := Date(time.Date(2021, time.December, 10, 0, 0, 0, 0, time.UTC))
+	withVATID := func(vatID string) *Contact {
+		contact := testContact()
+		contact.VATID = vatID
+		return contact
+	}
+	for _, test := range []struct{
+		input   string
+		from    *Contact
+		to      *Contact
+		err     error
+		legends []string
+		lines   int
+	}{
+		{
+			input: "d:Thing 1;r:EUR 10;t:20%,d:Thing 2;r:EUR 10,d:Thing 3;r:EUR 10;t:20%;cat:S",
+			lines: 2,
+		},
+		{
+			input:   "d:Consulting;r:EUR 1000;cat:reverse-charge",
+			from:    withVATID("GB123456789"),
+			to:      withVATID("DE123456789"),
+			legends: []string{"Reverse charge: the customer is liable to account for the VAT on this supply (Article 196, Council Directive 2006/112/EC). Customer VAT ID: DE123456789"},
+			lines:   1,
+		},
+		{
+			input: "d:Consulting;r:EUR 1000;cat:AE",
+			from:  withVATID("GB123456789"),
+			err:   errors.New("item \"Consulting\" has the tax category Reverse charge (AE) so the VAT ID of the To contact must be given"),
+		},
+		{
+			input: "d:Goods;r:EUR 1000;cat:K",
+			to:    withVATID("DE123456789"),
+			err:   errors.New("item \"Goods\" has the tax category Intra-community supply (K) so the VAT ID of the From contact must be given"),
+		},
+		{
+			input: "d:Insurance;r:EUR 1000;cat:E",
+			err:   errors.New("item \"Insurance\" has the tax category Exempt from tax (E) so an exemption reason must be given"),
+		},
+		{
+			input:   "d:Insurance;r:EUR 1000;cat:E;reason:VATEX-EU-132-1E,d:Training;r:EUR 100;cat:E;reason:VATEX-EU-132-1I,d:Other;r:EUR 10;cat:E;reason:VATEX-EU-132-1E",
+			legends: []string{"Exempt from VAT: VATEX-EU-132-1E", "Exempt from VAT: VATEX-EU-132-1I"},
+			lines:   1,
+		},
+		{
+			input: "d:Export;r:EUR 1000;t:20%;cat:G",
+			err:   errors.New("item \"Export\" has the tax category Export outside the EU (G) so cannot be taxed at 20%"),
+		},
+		{
+			input: "d:Thing;r:EUR 1000;cat:S",
+			err:   errors.New("item \"Thing\" has the tax category Standard rated (S) so must be taxed at a rate above 0%"),
+		},
+		{
+			input: "d:Thing;r:EUR 1000;cat:X",
+			err:   errors.New("\"X\" is not a valid tax category"),
+		},
+	} {
+		from, to := test.from, test.to
+		if from == nil {
+			from = testContact()
+		}
+		if to == nil {
+			to = testContact()
+		}
+		items := make(Items, 0)
+		err := items.Set(test.input)
+		var invoice *Invoice
+		if err == nil {
+			invoice, err = NewInvoice(1, from, to, &items, &Bank{}, &date, &date)
+		}
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("creating invoice with items \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("creating invoice with items \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("creating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else {
+			if legends := invoice.TaxLegends(); !reflect.DeepEqual(legends, append([]string{}, test.legends...)) {
+				t.Errorf("invoice with items \"%s\" should have the legends %v, instead it has: %v", test.input, test.legends, legends)
+			}
+			if summary, _ := invoice.TaxSummary(); len(summary.Lines) != test.lines {
+				t.Errorf("tax summary of items \"%s\" should have %d lines, instead it has: %d", test.input, test.lines, len(summary.Lines))
+			}
+			if _, err = invoice.Generate(); err != nil {
+				t.Errorf("generating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			}
+		}
+	}
+
+	// Missing contacts are reported using a RequiredFieldsError rather than panicking when their VAT IDs are checked
+	items := make(Items, 0)
+	if err := items.Set("d:Consulting;r:EUR 1000;cat:AE"); err != nil {
+		t.Fatalf("parsing items is not supposed To return error: \"%s\"", err.Error())
+	}
+	var requiredErr RequiredFieldsError
+	if _, err := NewInvoice(1, nil, withVATID("GB123456789"), &items, nil, &date, &date); !errors.As(err, &requiredErr) || requiredErr.Error() != "From" {
+		t.Errorf("creating an invoice without a From contact should return a RequiredFieldsError, got: %v", err)
+	}
+	invoice := &Invoice{Number: 1, From: withVATID("GB123456789"), Items: &items, InvoiceDate: &date, DueDate: &date}
+	if err := invoice.Validate(); !errors.As(err, &requiredErr) || requiredErr.Error() != "To" {
+		t.Errorf("validating an invoice without a To contact should return a RequiredFieldsError, got: %v", err)
+	}
+	if _, err := invoice.Generate(); !errors.As(err, &requiredErr) || requiredErr.Error() != "To" {
+		t.Errorf("generating an invoice without a To contact should return a RequiredFieldsError, got: %v", err)
+	}
+
+	// Invoices without dates, such as those decoded using DecodeInvoice, return a RequiredFieldsError rather than panicking
+	undated := &Invoice{Number: 1, From: testContact(), To: testContact(), Items: &items}
+	if err := undated.Validate(); !errors.As(err, &requiredErr) || requiredErr.Error() != "InvoiceDate, DueDate" {
+		t.Errorf("validating an invoice without dates should return a RequiredFieldsError, got: %v", err)
+	}
+	if _, err := undated.Total(); !errors.As(err, &requiredErr) || requiredErr.Error() != "InvoiceDate, DueDate" {
+		t.Errorf("totalling an invoice without dates should return a RequiredFieldsError, got: %v", err)
+	}
+	if _, err := undated.TaxSummary(); !errors.As(err, &requiredErr) {
+		t.Errorf("summarising the tax of an invoice without dates should return a RequiredFieldsError, got: %v", err)
+	}
+	undated.Terms = &PaymentTerms{NetDays: 30}
+	if _, err := undated.PaymentTerms(); !errors.As(err, &requiredErr) {
+		t.Errorf("describing the payment terms of an invoice without dates should return a RequiredFieldsError, got: %v", err)
+	}
+
+	// Invoices that have been changed since they were created are validated again before they are generated
+	invoice.To = withVATID("DE123456789")
+	if _, err := invoice.Generate(); err != nil {
+		t.Errorf("generating a valid reverse charge invoice is not supposed To return error: \"%s\"", err.Error())
+	}
+	invoice.To = testContact()
+	if _, err := invoice.Generate(); err == nil || !strings.Contains(err.Error(), "the VAT ID of the To contact must be given") {
+		t.Errorf("generating a reverse charge invoice whose To contact has no VAT ID should return an error, got: %v", err)
+	}
+}
+
+func TestInvoiceCompoundTax(t *testing.T) {
+	canadianDollar := *CurrencyFromAbbr("CAD")
+	for _, test := range []struct{
+		input      string
+		options    []InvoiceOption
+		components []string
+		taxes      []Money
+		tax        Money
+	}{
+		{
+			input:      "d:Thing 1;r:CAD 100;t:GST 5% (Canada) | QST 9.975% (Quebec),d:Thing 2;r:CAD 10;t:GST 5% (Canada)",
+			components: []string{"GST 5% (Canada)", "QST 9.975% (Quebec)"},
+			taxes:      []Money{{550, canadianDollar}, {998, canadianDollar}},
+			tax:        Money{1548, canadianDollar},
+		},
+		{
+			// QST used To be charged on the price including GST
+			input:      "d:Thing 1;r:CAD 100;t:GST 5% | QST 7.5% compound",
+			components: []string{"GST 5%", "QST 7.5% compound"},
+			taxes:      []Money{{500, canadianDollar}, {788, canadianDollar}},
+			tax:        Money{1288, canadianDollar},
+		},
+		{
+			input:      "d:Thing 1;r:CAD 112.88;t:GST 5% | QST 7.5% compound",
+			options:    []InvoiceOption{WithTaxInclusive()},
+			components: []string{"GST 5%", "QST 7.5% compound"},
+			taxes:      []Money{{500, canadianDollar}, {788, canadianDollar}},
+			tax:        Money{1288, canadianDollar},
+		},
+		{
+			input:      "d:Thing 1;r:USD 19.99;h:3;t:State 6% (California) | County 0.25% (Los Angeles) | District 3.25%",
+			components: []string{"State 6% (California)", "County 0.25% (Los Angeles)", "District 3.25%"},
+			taxes:      []Money{{360, UnitedStatesDollar}, {15, UnitedStatesDollar}, {195, UnitedStatesDollar}},
+			tax:        Money{570, UnitedStatesDollar},
+		},
+	} {
+		invoice, err := testInvoice(test.input, test.options...)
+		if err != nil {
+			t.Errorf("creating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		summary, err := invoice.TaxSummary()
+		if err != nil {
+			t.Errorf("summarising the tax of items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		if *summary.Tax != test.tax {
+			t.Errorf("items \"%s\" should have a total tax of %v, got: %v", test.input, &test.tax, summary.Tax)
+		}
+		if len(summary.Components) != len(test.components) {
+			t.Errorf("items \"%s\" should have %d tax components, got: %d", test.input, len(test.components), len(summary.Components))
+			continue
+		}
+		for n, component := range summary.Components {
+			if component.Component.String() != test.components[n] || *component.Tax != test.taxes[n] {
+				t.Errorf("tax component %d of items \"%s\" should be %s: %v, got: %s: %v", n + 1, test.input, test.components[n], &test.taxes[n], component.Component.String(), component.Tax)
+			}
+		}
+		if _, err = invoice.Generate(); err != nil {
+			t.Errorf("generating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		}
+	}
+}
+
+func TestInvoiceDeductions(t *testing.T) {
+	for _, test := range []struct{
+		input       string
+		deductions  string
+		err         error
+		net         Money
+		tax         Money
+		withholding Money
+		payable     Money
+	}{
+		{
+			input:       "d:Consulting;h:10;r:EUR 100;t:21%",
+			deductions:  "IRPF 15%",
+			net:         Money{100000, Euro},
+			tax:         Money{21000, Euro},
+			withholding: Money{15000, Euro},
+			payable:     Money{106000, Euro},
+		},
+		{
+			input:       "d:Consulenza;r:EUR 1234.56;t:22%",
+			deductions:  "Ritenuta d'acconto 20 %, Retention EUR 50",
+			net:         Money{123456, Euro},
+			tax:         Money{27160, Euro},
+			withholding: Money{29691, Euro},
+			payable:     Money{120925, Euro},
+		},
+		{
+			input:       "d:Consulting;r:EUR 100",
+			net:         Money{10000, Euro},
+			tax:         Money{0, Euro},
+			withholding: Money{0, Euro},
+			payable:     Money{10000, Euro},
+		},
+		{
+			input:      "d:Consulting;r:EUR 100",
+			deductions: "Retention GBP 50",
+			err:        errors.New("deduction \"Retention GBP 50.00\" is in GBP but the invoice is in EUR"),
+		},
+		{
+			input:      "d:Consulting;r:EUR 100",
+			deductions: "IRPF",
+			err:        errors.New("\"IRPF\" is not a valid deduction"),
+		},
+	} {
+		var deductions Deductions
+		var invoice *Invoice
+		var err error
+		if test.deductions != "" {
+			err = deductions.Set(test.deductions)
+		}
+		if err == nil {
+			invoice, err = testInvoice(test.input, WithDeductions(deductions...))
+		}
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("creating invoice with deductions \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.deductions, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("creating invoice with deductions \"%s\" does not return the expected error: \"%s\"", test.deductions, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("creating invoice with deductions \"%s\" is not supposed To return error: \"%s\"", test.deductions, err.Error())
+		} else {
+			net, _ := invoice.NetTotal()
+			tax, _ := invoice.TaxTotal()
+			withholding, _ := invoice.Withholding()
+			payable, _ := invoice.Payable()
+			if *net != test.net || *tax != test.tax || *withholding != test.withholding || *payable != test.payable {
+				t.Errorf("invoice with deductions \"%s\" should have (net: %v, tax: %v, withholding: %v, payable: %v), got: (net: %v, tax: %v, withholding: %v, payable: %v)", test.deductions, &test.net, &test.tax, &test.withholding, &test.payable, net, tax, withholding, payable)
+			}
+			if _, err = invoice.Generate(); err != nil {
+				t.Errorf("generating invoice with deductions \"%s\" is not supposed To return error: \"%s\"", test.deductions, err.Error())
+			}
+		}
+	}
+}
+
+func TestInvoiceDiscount(t *testing.T) {
+	for _, test := range []struct{
+		input     string
+		discount  string
+		surcharge bool
+		err       error
+		net       Money
+		tax       Money
+		discTotal Money
+	}{
+		{
+			input:     "d:Thing;h:2;r:GBP 50;disc:10%;t:20%",
+			net:       Money{9000, GreatBritishPound},
+			tax:       Money{1800, GreatBritishPound},
+			discTotal: Money{0, GreatBritishPound},
+		},
+		{
+			input:     "d:Thing;r:GBP 100;disc:GBP 5;t:20%",
+			net:       Money{9500, GreatBritishPound},
+			tax:       Money{1900, GreatBritishPound},
+			discTotal: Money{0, GreatBritishPound},
+		},
+		{
+			input:     "d:Thing 1;r:GBP 100;t:20%, d:Thing 2;r:GBP 50",
+			discount:  "10%",
+			net:       Money{13500, GreatBritishPound},
+			tax:       Money{1800, GreatBritishPound},
+			discTotal: Money{-1500, GreatBritishPound},
+		},
+		{
+			input:     "d:Thing 1;r:GBP 100;t:20%, d:Thing 2;r:GBP 50",
+			discount:  "GBP 30",
+			net:       Money{12000, GreatBritishPound},
+			tax:       Money{1600, GreatBritishPound},
+			discTotal: Money{-3000, GreatBritishPound},
+		},
+		{
+			input:     "d:Thing 1;r:GBP 100;t:20%;incl:true, d:Thing 2;r:GBP 50;disc:GBP 10",
+			discount:  "50%",
+			net:       Money{6167, GreatBritishPound},
+			tax:       Money{833, GreatBritishPound},
+			discTotal: Money{-7000, GreatBritishPound},
+		},
+		{
+			input:     "d:Thing;r:GBP 100;t:20%",
+			discount:  "10%",
+			net:       Money{9000, GreatBritishPound},
+			tax:       Money{1800, GreatBritishPound},
+			discTotal: Money{-1000, GreatBritishPound},
+		},
+		{
+			input:     "d:Thing;r:GBP 100;t:20%",
+			discount:  "5%",
+			surcharge: true,
+			net:       Money{10500, GreatBritishPound},
+			tax:       Money{2100, GreatBritishPound},
+			discTotal: Money{500, GreatBritishPound},
+		},
+		{
+			input:    "d:Thing;r:GBP 100",
+			discount: "USD 10",
+			err:      errors.New("discount \"USD 10.00\" is in USD but the invoice is in GBP"),
+		},
+		{
+			input:    "d:Thing;r:GBP 100",
+			discount: "110%",
+			err:      errors.New("\"110%\" is not a valid discount"),
+		},
+		{
+			input: "d:Thing;r:GBP 100;disc:USD 10",
+			err:   errors.New("item \"Thing\" has a discount in USD but a rate in GBP"),
+		},
+		{
+			input:    "d:Thing 1;r:GBP 100;t:20%, d:Thing 2;r:GBP 50",
+			discount: "GBP 150.01",
+			err:      errors.New("discount GBP 150.01 is larger than the total of GBP 150.00 that it is taken off"),
+		},
+		{
+			input:     "d:Thing 1;r:GBP 100;t:20%, d:Thing 2;r:GBP 50",
+			discount:  "GBP 150",
+			net:       Money{0, GreatBritishPound},
+			tax:       Money{0, GreatBritishPound},
+			discTotal: Money{-15000, GreatBritishPound},
+		},
+		{
+			input:     "d:Thing;r:GBP 100",
+			discount:  "GBP 150",
+			surcharge: true,
+			net:       Money{25000, GreatBritishPound},
+			tax:       Money{0, GreatBritishPound},
+			discTotal: Money{15000, GreatBritishPound},
+		},
+		{
+			input: "d:Thing;h:2;r:GBP 50;disc:GBP 100.01",
+			err:   errors.New("item \"Thing\" has a discount of GBP 100.01, which is larger than its amount of GBP 100.00"),
+		},
+	} {
+		discount := &Discount{Surcharge: test.surcharge}
+		var invoice *Invoice
+		var err error
+		if test.discount != "" {
+			err = discount.Set(test.discount)
+		}
+		if err == nil {
+			invoice, err = testInvoice(test.input, WithDiscount(discount))
+		}
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("creating invoice \"%s\" with discount \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.discount, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("creating invoice \"%s\" with discount \"%s\" does not return the expected error: \"%s\"", test.input, test.discount, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("creating invoice \"%s\" with discount \"%s\" is not supposed To return error: \"%s\"", test.input, test.discount, err.Error())
+		} else {
+			net, _ := invoice.NetTotal()
+			tax, _ := invoice.TaxTotal()
+			discTotal, _ := invoice.DiscountTotal()
+			if *net != test.net || *tax != test.tax || *discTotal != test.discTotal {
+				t.Errorf("invoice \"%s\" with discount \"%s\" should have (net: %v, tax: %v, discount: %v), got: (net: %v, tax: %v, discount: %v)", test.input, test.discount, &test.net, &test.tax, &test.discTotal, net, tax, discTotal)
+			}
+			if _, err = invoice.Generate(); err != nil {
+				t.Errorf("generating invoice \"%s\" with discount \"%s\" is not supposed To return error: \"%s\"", test.input, test.discount, err.Error())
+			}
+
+			// The item table must add up To the total of the invoice, including the change in tax caused by the discount
+			total, _ := invoice.Total()
+			contents, _ := invoice.getContents(true, false)
+			if shown, err := tableTotal(contents); err != nil || *shown != *total {
+				t.Errorf("the item table of invoice \"%s\" with discount \"%s\" should add up To %v, got: %v (%v)", test.input, test.discount, total, shown, err)
+			}
+		}
+	}
+}
+
+// tableTotal returns the sum of the last column of the given item table, skipping the rows that contain the subtotal of
+// an ItemGroup.
+func tableTotal(contents [][]string) (*Money, error) {
+	total := &Money{0, ZeroCurrency}
+	for _, row := range contents {
+		cell := strings.TrimSuffix(row[len(row) - 1], "*")
+		if cell == "" || strings.HasSuffix(row[0], " subtotal") {
+			continue
+		}
+		amount, err := ParseMoney(cell)
+		if err != nil {
+			return nil, err
+		}
+		total = total.Add(amount)
+	}
+	return total, nil
+}
+
+func TestPaymentTerms(t *testing.T) {
+	for _, test := range []struct{
+		input string
+		err   error
+		out   PaymentTerms
+		str   string
+	}{
+		{
+			input: "2/10 net 30",
+			out:   PaymentTerms{DiscountPercent: big.NewRat(2, 1), DiscountDays: 10, NetDays: 30},
+			str:   "2/10 net 30",
+		},
+		{
+			input: "1.5%/7, interest 13.25%, compensation statutory",
+			out:   PaymentTerms{DiscountPercent: big.NewRat(3, 2), DiscountDays: 7, InterestRate: big.NewRat(53, 4), StatutoryCompensation: true},
+			str:   "1.5/7, interest 13.25%, compensation statutory",
+		},
+		{
+			input: "NET 14, Compensation GBP 40",
+			out:   PaymentTerms{NetDays: 14, Compensation: &Money{4000, GreatBritishPound}},
+			str:   "net 14, compensation GBP 40.00",
+		},
+		{
+			input: "5/30 net 14",
+			err:   errors.New("the early payment discount cannot last longer than the 14 days until payment is due"),
+		},
+		{
+			input: "net 30, interest lots",
+			err:   errors.New("\"interest lots\" is not a valid interest rate"),
+		},
+		{
+			input: "net 30, compensation GBP -40",
+			err:   errors.New("\"compensation GBP -40\" is not a valid compensation"),
+		},
+		{
+			input: "pay soon",
+			err:   errors.New("\"pay soon\" are not valid payment terms"),
+		},
+	} {
+		var terms PaymentTerms
+		err := terms.Set(test.input)
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("parsing PaymentTerms \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("parsing PaymentTerms \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("parsing PaymentTerms \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else {
+			if !reflect.DeepEqual(terms, test.out) {
+				t.Errorf("expected output (%+v) does not match actual output: %+v", test.out, terms)
+			}
+			if terms.String() != test.str {
+				t.Errorf("PaymentTerms \"%s\" should be printed as \"%s\", not \"%s\"", test.input, test.str, terms.String())
+			}
+		}
+	}
+}
+
+func TestInvoiceAmountDue(t *testing.T) {
+	invoiceDate := Date(time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC))
+	for _, test := range []struct{
+		input string
+		terms string
+		due   *Date
+		err   error
+		// The amount due on each number of days after the invoice date
+		amounts map[int]Money
+	}{
+		{
+			input:   "d:Thing;r:GBP 1000;t:20%",
+			terms:   "2/10 net 30, interest 13.25%, compensation statutory",
+			amounts: map[int]Money{
+				0:  {117600, GreatBritishPound},
+				10: {117600, GreatBritishPound},
+				11: {120000, GreatBritishPound},
+				30: {120000, GreatBritishPound},
+				// 1200 × 13.25% × 1/365 = 0.44 plus GBP 70.00 as the debt is over GBP 1000.00
+				31: {127044, GreatBritishPound},
+				60: {128307, GreatBritishPound},
+			},
+		},
+		{
+			input:   "d:Thing;r:EUR 500",
+			terms:   "net 14, compensation EUR 40",
+			amounts: map[int]Money{
+				14: {50000, Euro},
+				15: {54000, Euro},
+			},
+		},
+		{
+			input:   "d:Thing;r:GBP -500",
+			terms:   "2/10 net 30, interest 8%",
+			amounts: map[int]Money{
+				0:  {-50000, GreatBritishPound},
+				40: {-50000, GreatBritishPound},
+			},
+		},
+		{
+			input: "d:Thing;r:GBP 100",
+			terms: "net 30",
+			due:   invoiceDate.AddDays(14),
+			err:   errors.New("the payment terms are net 30 but the due date is 14 days after the invoice date"),
+		},
+		{
+			input: "d:Thing;r:GBP 100",
+			terms: "2/10",
+			due:   invoiceDate.AddDays(7),
+			err:   errors.New("the early payment discount lasts 10 days but the due date is 7 days after the invoice date"),
+		},
+		{
+			input: "d:Thing;r:EUR 100",
+			terms: "net 30, compensation statutory",
+			err:   errors.New("statutory compensation can only be charged on invoices in GBP, not EUR"),
+		},
+	} {
+		var terms PaymentTerms
+		if err := terms.Set(test.terms); err != nil {
+			t.Errorf("parsing PaymentTerms \"%s\" is not supposed To return error: \"%s\"", test.terms, err.Error())
+			continue
+		}
+		due := test.due
+		if due == nil {
+			due = invoiceDate.AddDays(int(terms.NetDays))
+		}
+		items := make(Items, 0)
+		if err := items.Set(test.input); err != nil {
+			t.Errorf("parsing Items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		invoice, err := NewInvoice(1, testContact(), testContact(), &items, &Bank{}, &invoiceDate, due, WithPaymentTerms(&terms))
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("creating invoice with terms \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.terms, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("creating invoice with terms \"%s\" does not return the expected error: \"%s\"", test.terms, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("creating invoice with terms \"%s\" is not supposed To return error: \"%s\"", test.terms, err.Error())
+		} else {
+			for days, expected := range test.amounts {
+				amount, _ := invoice.AmountDue(invoiceDate.AddDays(days))
+				if *amount != expected {
+					t.Errorf("invoice with terms \"%s\" should have %v due %d days after the invoice date, not %v", test.terms, &expected, days, amount)
+				}
+			}
+			if _, err = invoice.Generate(); err != nil {
+				t.Errorf("generating invoice with terms \"%s\" is not supposed To return error: \"%s\"", test.terms, err.Error())
+			}
+		}
+	}
+}
+
+func TestInvoiceQuantityHeader(t *testing.T) {
+	for _, test := range []struct{
+		input     string
+		header    string
+		showUnits bool
+		cell      string
+	}{
+		{"d:Thing 1;r:GBP 12", "Hours/Quantity", false, "1"},
+		{"d:Thing 1;h:7.5;u:hrs;r:GBP 12,d:Thing 2;h:1;u:HUR;r:GBP 5", "Hours", false, "7.5"},
+		{"d:Thing 1;h:2.5;u:kg;r:GBP 12", "Quantity (kg)", false, "2.5"},
+		{"d:Thing 1;h:1;u:day;r:GBP 12,d:Thing 2;r:GBP 5", "Quantity", true, "1 day"},
+		{"d:Thing 1;h:0.5;u:XYZ;r:GBP 12,d:Thing 2;u:pcs;r:GBP 5", "Quantity", true, "0.5 XYZ"},
+	} {
+		invoice, err := testInvoice(test.input)
+		if err != nil {
+			t.Errorf("creating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		header, showUnits := invoice.quantityHeader()
+		if header != test.header || showUnits != test.showUnits {
+			t.Errorf("invoice with items \"%s\" should have the quantity header (%s, %t), not (%s, %t)", test.input, test.header, test.showUnits, header, showUnits)
+		}
+		contents, _ := invoice.getContents(false, false)
+		if contents[0][1] != test.cell {
+			t.Errorf("the first item of invoice with items \"%s\" should have the quantity \"%s\", not \"%s\"", test.input, test.cell, contents[0][1])
+		}
+	}
+}
+
+func TestInvoiceItemDetails(t *testing.T) {
+	invoice, err := testInvoice("d:Thing 1;r:GBP 60;sku:ABC-123;po:PO-1;period:1/12/2021-31/12/2021, d:Thing 2;r:GBP 5, d:Thing 3;r:GBP 5;note:Extra")
+	if err != nil {
+		t.Fatalf("creating invoice is not supposed To return error: \"%s\"", err.Error())
+	}
+	contents, _ := invoice.getContents(false, false)
+	expected := []string{
+		"Thing 1",
+		"SKU: ABC-123, Buyer ref: PO-1, Period: December 1st, 2021 - December 31st, 2021",
+		"Thing 2",
+		"Thing 3",
+		"Note: Extra",
+	}
+	if len(contents) != len(expected) {
+		t.Fatalf("the item table should have %d rows, not %d", len(expected), len(contents))
+	}
+	for n, row := range contents {
+		if row[0] != expected[n] {
+			t.Errorf("row %d of the item table should start with \"%s\", not \"%s\"", n + 1, expected[n], row[0])
+		}
+	}
+}
+
+func TestInvoiceGroups(t *testing.T) {
+	for _, test := range []struct{
+		input   string
+		options []InvoiceOption
+		// The first cell of each row of the item table
+		rows    []string
+		// The subtotal of each ItemGroup
+		totals  []string
+	}{
+		{
+			input:  "d:Thing 1;r:GBP 10;t:20%, d:Thing 2;r:GBP 5",
+			rows:   []string{"Thing 1", "Thing 2"},
+			totals: []string{},
+		},
+		{
+			input:  "d:Thing 1;r:GBP 10;t:20%;group:Discovery, d:Thing 2;r:GBP 5;section:Build, d:Thing 3;h:2;r:GBP 7.50;phase:Discovery",
+			rows:   []string{"Discovery", "Thing 1", "Thing 3", "Discovery subtotal", "Build", "Thing 2", "Build subtotal"},
+			totals: []string{"GBP 27.00", "GBP 5.00"},
+		},
+		{
+			input:  "d:Thing 1;r:GBP 10;group:Build, d:Thing 2;r:GBP 5;sku:ABC",
+			rows:   []string{"Build", "Thing 1", "Build subtotal", "Other", "Thing 2", "SKU: ABC", "Other subtotal"},
+			totals: []string{"GBP 10.00", "GBP 5.00"},
+		},
+		{
+			input:   "d:Thing 1;r:GBP 100;t:20%;group:Build, d:Thing 2;r:GBP 50;t:20%;group:Run",
+			options: []InvoiceOption{WithDiscount(&Discount{Percent: big.NewRat(10, 1)})},
+			rows:    []string{"Build", "Thing 1", "Build subtotal", "Run", "Thing 2", "Run subtotal", "Discount 10% (before tax)", "Tax on discount 10%"},
+			totals:  []string{"GBP 120.00", "GBP 60.00"},
+		},
+		{
+			input:   "d:Thing 1;r:GBP 100;t:20%;group:Build, d:Thing 2;r:USD 40;t:20%;group:Run",
+			options: []InvoiceOption{WithConversion(GreatBritishPound, ExchangeRates{{UnitedStatesDollar, GreatBritishPound, big.NewRat(3, 4), Date(time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC))}})},
+			rows:    []string{"Build", "Thing 1", "Build subtotal", "Run", "Thing 2", "Run subtotal"},
+			totals:  []string{"GBP 120.00", "GBP 36.00"},
+		},
+	} {
+		invoice, err := testInvoice(test.input, test.options...)
+		if err != nil {
+			t.Errorf("creating invoice with items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		contents, _ := invoice.getContents(false, len(invoice.UsedExchangeRates()) > 0)
+		first := make([]string, len(contents))
+		totals := make([]string, 0)
+		last := -1
+		for n, row := range contents {
+			first[n] = row[0]
+			if strings.HasSuffix(row[0], " subtotal") {
+				totals = append(totals, row[len(row) - 1])
+				last = n
+			}
+		}
+		if !reflect.DeepEqual(first, test.rows) || !reflect.DeepEqual(totals, test.totals) {
+			t.Errorf("invoice with items \"%s\" should have the rows %v and the group subtotals %v, not %v and %v", test.input, test.rows, test.totals, first, totals)
+		}
+
+		// The subtotals of the groups, followed by the rows of the Discount of the invoice, must add up To the total of
+		// the invoice
+		if last < 0 {
+			continue
+		}
+		total, _ := invoice.Total()
+		groupTotal := &Money{0, total.Currency}
+		for n, row := range contents {
+			if n <= last && !strings.HasSuffix(row[0], " subtotal") {
+				continue
+			}
+			amount, err := ParseMoney(row[len(row) - 1])
+			if err != nil {
+				t.Fatalf("the last column of row \"%s\" is not supposed To return error: \"%s\"", row[0], err.Error())
+			}
+			groupTotal = groupTotal.Add(amount)
+		}
+		if *groupTotal != *total {
+			t.Errorf("the groups of invoice with items \"%s\" add up To %v, not the total of %v", test.input, groupTotal, total)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/andygello555/ginvoice/globals"
+	"github.com/andygello555/gotils/misc"
+	str "github.com/andygello555/gotils/strings"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// KeyValueFlags are flag.Value(s) that are set From a list of key-value pairs (e.g. "first: John, last: Smith").
+//
+// The fields of a KeyValueFlags struct are described using struct tags:
+//  - kv: the comma-separated keys that the field can be given by (case insensitive). The first key is the name of
+//    the field. Fields without a kv tag cannot be set.
+//  - required: whether the field must be given ("true").
+//  - default: the value that the field is set To if it is not given.
+//  - validate: the name of the validator in keyValueValidators that the field is checked with once it is set.
+// For example:
+//  Email string `kv:"email,e" required:"true" validate:"email"`
+type KeyValueFlags interface {
+	// KeyVal sets the field given by the key of the given key-value pair, using the given ParserConfig, and returns a
+	// pointer To it. This is usually implemented using keyValLogic.
+	KeyVal(keyVal string, config *ParserConfig) (interface{}, error)
+}
+
+// ParserConfig is the configuration of the flag grammar that KeyValueFlags, and the other flag.Value(s) containing
+// lists, are parsed and formatted with.
+type ParserConfig struct {
+	Separators   globals.Separators
+	// Locale is the Locale used To parse Money and the decimal separator of Quantity(s). If this is nil then the
+	// DefaultLocale is used.
+	Locale       *Locale
+	// Precision is the maximum number of decimal places that a Quantity can be given To. If this is nil then
+	// QuantityPrecision is used.
+	Precision    *uint
+	// TimeRounding is the TimeRounding used when a Quantity is given as a duration. If this is nil then the
+	// DefaultTimeRounding is used.
+	TimeRounding *TimeRounding
+}
+
+// DefaultParserConfig is the ParserConfig used by the Set, String and Format methods of each flag.Value. Its
+// Separators and Locale can be changed before any flags are parsed (e.g. by the -separators and -locale flags), and
+// its Precision and TimeRounding point To QuantityPrecision and the DefaultTimeRounding. Other callers should give
+// their own ParserConfig To the SetWith and FormatWith methods instead.
+var DefaultParserConfig = &ParserConfig{
+	Separators:   globals.DefaultSeparators(),
+	Locale:       DefaultLocale(),
+	Precision:    &QuantityPrecision,
+	TimeRounding: DefaultTimeRounding,
+}
+
+// locale returns the Locale of the ParserConfig, or the DefaultLocale if it has none.
+func (c *ParserConfig) locale() *Locale {
+	if c.Locale == nil {
+		return DefaultLocale()
+	}
+	return c.Locale
+}
+
+// precision returns the Precision of the ParserConfig, or QuantityPrecision if it has none.
+func (c *ParserConfig) precision() uint {
+	if c.Precision == nil {
+		return QuantityPrecision
+	}
+	return *c.Precision
+}
+
+// timeRounding returns the TimeRounding of the ParserConfig, or the DefaultTimeRounding if it has none.
+func (c *ParserConfig) timeRounding() *TimeRounding {
+	if c.TimeRounding == nil {
+		return DefaultTimeRounding
+	}
+	return c.TimeRounding
+}
+
+// keyValueChecker is implemented by KeyValueFlags with fields that depend on each other, and so can only be defaulted
+// or validated once all the key-value pairs have been set.
+type keyValueChecker interface {
+	check() error
+}
+
+// setter is implemented by all the flag.Value(s) that can be set From a string.
+type setter interface {
+	Set(value string) error
+}
+
+// configSetter is implemented by the flag.Value(s) whose Set method depends on the DefaultParserConfig, so that they
+// can be set using the ParserConfig of the value they are part of instead.
+type configSetter interface {
+	SetWith(config *ParserConfig, value string) error
+}
+
+// keyValueValidators are the validators that can be given in the validate struct tag of a field of a KeyValueFlags.
+// Each is given a pointer To the field once it has been set, so that it can also normalise its value.
+var keyValueValidators = map[string]func(field interface{}) error{
+	"email":     validateEmail,
+	"vatid":     validateVATID,
+	"accountno": validateDigits("account number", 8),
+	"sortcode":  validateDigits("sort code", 6),
+}
+
+// vatIDRegex (const) matches VAT identification numbers once spaces, dots and hyphens have been removed.
+var vatIDRegex = regexp.MustCompile("^[A-Z]{2}[0-9A-Z+*]{2,13}$")
+
+func validateEmail(field interface{}) error {
+	email := *field.(*string)
+	if !misc.IsEmailValid(email) {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid email", email))
+	}
+	return nil
+}
+
+func validateVATID(field interface{}) error {
+	value := field.(*string)
+	vatID := strings.ToUpper(strings.NewReplacer(" ", "", ".", "", "-", "").Replace(*value))
+	if !vatIDRegex.MatchString(vatID) {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid VAT ID, VAT IDs must start with a 2 letter country prefix (e.g. DE123456789)", *value))
+	}
+	*value = vatID
+	return nil
+}
+
+// validateDigits returns a validator that checks that a field is a number with the given number of digits.
+func validateDigits(name string, digits int) func(field interface{}) error {
+	return func(field interface{}) error {
+		value := *field.(*string)
+		var errStr string
+		if len(value) != digits {
+			errStr += fmt.Sprintf(" (%d digits)", digits)
+		}
+		if !str.IsNumeric(value) {
+			errStr += " (not numeric)"
+		}
+		if errStr != "" {
+			return errors.New(fmt.Sprintf("\"%s\" is not a valid %s%s", value, name, errStr))
+		}
+		return nil
+	}
+}
+
+// keyValueField is a field of a KeyValueFlags struct along with the options given in its struct tags.
+type keyValueField struct {
+	name      string
+	keys      []string
+	required  bool
+	def       string
+	validator string
+	// ptr is a pointer To the field.
+	ptr       interface{}
+}
+
+// keyValueFields returns the fields of the given KeyValueFlags that have a kv struct tag, in the order that they are
+// declared.
+func keyValueFields(t KeyValueFlags) []*keyValueField {
+	value := reflect.ValueOf(t).Elem()
+	fields := make([]*keyValueField, 0)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag, ok := field.Tag.Lookup("kv")
+		if !ok {
+			continue
+		}
+		required, _ := strconv.ParseBool(field.Tag.Get("required"))
+		fields = append(fields, &keyValueField{
+			name:      field.Name,
+			keys:      strings.Split(tag, ","),
+			required:  required,
+			def:       field.Tag.Get("default"),
+			validator: field.Tag.Get("validate"),
+			ptr:       value.Field(i).Addr().Interface(),
+		})
+	}
+	return fields
+}
+
+// set the field From the given value using the given ParserConfig, which is then validated using the field's
+// validator. Lists are split using the given Splitter before each part is unquoted, otherwise the value is unquoted
+// before it is set. Taxes are split and unquoted by Taxes.set.
+func (f *keyValueField) set(val string, config *ParserConfig, listSplit *globals.Splitter) error {
+	switch prop := f.ptr.(type) {
+	case *[]string:
+		parts := listSplit.Split(val, -1)
+		for i, part := range parts {
+			unquoted, err := globals.Unquote(part)
+			if err != nil {
+				return err
+			}
+			parts[i] = unquoted
+		}
+		*prop = parts
+	case *Taxes:
+		if err := prop.set(val, listSplit); err != nil {
+			return err
+		}
+	default:
+		unquoted, err := globals.Unquote(val)
+		if err != nil {
+			return err
+		}
+		if err = f.setUnquoted(unquoted, config); err != nil {
+			return err
+		}
+	}
+
+	if f.validator != "" {
+		validator, ok := keyValueValidators[f.validator]
+		if !ok {
+			return errors.New(fmt.Sprintf("field \"%s\" has an unknown validator \"%s\"", f.name, f.validator))
+		}
+		return validator(f.ptr)
+	}
+	return nil
+}
+
+// setUnquoted sets the field From the given value once it has been unquoted, using the given ParserConfig.
+func (f *keyValueField) setUnquoted(val string, config *ParserConfig) error {
+	switch prop := f.ptr.(type) {
+	case *string:
+		*prop = val
+	case *uint:
+		i, err := strconv.ParseUint(val, 10, 0)
+		if err != nil {
+			return err
+		}
+		*prop = uint(i)
+	case *bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		*prop = b
+	case *Money:
+		m, err := ParseMoneyLocale(val, config.locale())
+		if err != nil {
+			return err
+		}
+		*prop = *m
+	case configSetter:
+		return prop.SetWith(config, val)
+	case setter:
+		return prop.Set(val)
+	default:
+		return errors.New(fmt.Sprintf("cannot set field of type \"%s\" To \"%v\"", str.TypeName(f.ptr), val))
+	}
+	return nil
+}
+
+// keyValLogic sets the field of the given KeyValueFlags whose kv struct tag contains the key of the given key-value
+// pair. A pointer To the field is returned. The key is split From the value using the key-value separator of the given
+// ParserConfig and lists are split using the given Splitter.
+//
+// An UnknownKeyError is returned if the key-value pair has no key or an unknown key, and a ValidationError is returned
+// if the value cannot be set. The Offset of each is relative To the start of the key-value pair.
+func keyValLogic(keyVal string, t KeyValueFlags, config *ParserConfig, listSplit *globals.Splitter) (interface{}, error) {
+	keyValSplit, offsets := config.Separators.KeyValueSplit().SplitOffsets(keyVal, 2)
+	keyValName := reflect.TypeOf(t).Elem().Name()
+	fields := keyValueFields(t)
+
+	// If we don't find a key and a value then we fill out an appropriate error
+	if len(keyValSplit) != 2 {
+		// Create a nice help text lookup
+		var builder strings.Builder
+		for _, field := range fields {
+			builder.WriteString("\t- The following are all possible prefixes for \"" + field.name + "\"\n")
+			for _, possible := range field.keys {
+				builder.WriteString("\t\t- " + possible + "\n")
+			}
+		}
+		builder.WriteString("\n")
+		return nil, &UnknownKeyError{Type: keyValName, Input: keyVal, sep: config.Separators.KeyValue, help: builder.String()}
+	}
+
+	key := strings.ToLower(keyValSplit[0])
+	keys := make([]string, 0)
+	for _, field := range fields {
+		for _, possible := range field.keys {
+			if possible == key {
+				if err := field.set(keyValSplit[1], config, listSplit); err != nil {
+					return field.ptr, &ValidationError{Type: keyValName, Field: field.name, Input: keyValSplit[1], Offset: offsets[1], Err: err}
+				}
+				return field.ptr, nil
+			}
+		}
+		keys = append(keys, field.keys...)
+	}
+	return nil, &UnknownKeyError{Type: keyValName, Key: keyValSplit[0], Input: keyVal, Suggestions: suggestKeys(key, keys)}
+}
+
+// setLogic splits the given value into key-value pairs using the given Splitter, which are then set using setKeyVals.
+// The Offset of any error is relative To the start of the value.
+func setLogic(value string, t KeyValueFlags, config *ParserConfig, split *globals.Splitter) error {
+	keyVals, offsets := split.SplitOffsets(value, -1)
+	err := setKeyVals(keyVals, offsets, t, config)
+	if missing, ok := err.(*MissingFieldsError); ok {
+		missing.Input, missing.Offset = value, 0
+	}
+	return err
+}
+
+// setKeyVals sets the fields of the given KeyValueFlags using each of the given key-value pairs, which start at the
+// given offsets of the value being parsed. The offsets can be nil if they are not known.
+//
+// A DuplicateKeyError is returned if a field is given more than once and a MissingFieldsError is returned if a
+// required field is not given. Fields that are not given are set To their default, and then the KeyValueFlags is
+// checked if it is a keyValueChecker.
+func setKeyVals(keyVals []string, offsets []int, t KeyValueFlags, config *ParserConfig) error {
+	typeName := reflect.TypeOf(t).Elem().Name()
+	fields := keyValueFields(t)
+	foundKeysSet := make(map[interface{}]struct{})
+	for n, keyVal := range keyVals {
+		offset := -1
+		if offsets != nil {
+			offset = offsets[n]
+		}
+		fieldP, err := t.KeyVal(keyVal, config)
+		if err != nil {
+			return shiftError(err, offset)
+		}
+		if _, ok := foundKeysSet[fieldP]; ok {
+			duplicate := &DuplicateKeyError{Type: typeName, Input: keyVal, Offset: offset}
+			for _, field := range fields {
+				if field.ptr == fieldP {
+					duplicate.Field = field.name
+				}
+			}
+			return duplicate
+		}
+		foundKeysSet[fieldP] = struct{}{}
+	}
+
+	// Here we list all the required fields that were not given, and default the optional ones
+	missing := &MissingFieldsError{Type: typeName, Offset: -1}
+	for _, field := range fields {
+		if _, ok := foundKeysSet[field.ptr]; ok {
+			continue
+		}
+		switch {
+		case field.required:
+			missing.Fields = append(missing.Fields, field.name)
+			missing.keys = append(missing.keys, field.keys)
+		case field.def != "":
+			if err := field.set(field.def, config, config.Separators.FirstLevelSplit()); err != nil {
+				return err
+			}
+		}
+	}
+	if len(missing.Fields) > 0 {
+		return missing
+	}
+
+	if checker, ok := t.(keyValueChecker); ok {
+		return checker.check()
+	}
+	return nil
+}
+
+// format returns the value of the field in the same syntax that it is parsed From. Values are quoted if they contain
+// one of the Separators of the given ParserConfig, and the parts of lists are joined using the given separator.
+func (f *keyValueField) format(config *ParserConfig, listSep string) string {
+	quote := config.Separators.Quote
+	switch prop := f.ptr.(type) {
+	case *string:
+		return quote(*prop)
+	case *[]string:
+		parts := make([]string, len(*prop))
+		for i, part := range *prop {
+			parts[i] = quote(part)
+		}
+		return strings.Join(parts, listSep + " ")
+	case *Taxes:
+		return prop.format(config, listSep)
+	case *uint:
+		return strconv.FormatUint(uint64(*prop), 10)
+	case *bool:
+		return strconv.FormatBool(*prop)
+	case *Money:
+		return quote(config.locale().Abbr(prop))
+	case *Discount:
+		return quote(prop.format(config))
+	case *Quantity:
+		return quote(prop.format())
+	case *Period:
+		return quote(prop.format())
+	case fmt.Stringer:
+		return quote(prop.String())
+	default:
+		return quote(fmt.Sprintf("%v", reflect.ValueOf(f.ptr).Elem().Interface()))
+	}
+}
+
+// formatKeyVals returns the fields of the given KeyValueFlags that are required or not zero as key-value pairs, in the
+// same syntax that they are parsed From by setLogic. Each pair is given using the first key of its field and the pairs are joined
+// using the given separator. Lists within the pairs are joined using listSep.
+func formatKeyVals(t KeyValueFlags, config *ParserConfig, sep string, listSep string) string {
+	pairs := make([]string, 0)
+	for _, field := range keyValueFields(t) {
+		if !field.required && reflect.ValueOf(field.ptr).Elem().IsZero() {
+			continue
+		}
+		pairs = append(pairs, field.keys[0] + config.Separators.KeyValue + " " + field.format(config, listSep))
+	}
+	return strings.Join(pairs, sep + " ")
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UnknownKeyError is returned when a key-value pair of a KeyValueFlags has no key, or has a key that does not belong
+// To any of its fields.
+type UnknownKeyError struct {
+	// Type is the name of the KeyValueFlags.
+	Type        string
+	// Key is the unknown key, which is empty if the key-value pair has no key.
+	Key         string
+	// Input is the key-value pair.
+	Input       string
+	// Offset is the byte offset of Input within the value that was being parsed, or -1 if it is not known.
+	Offset      int
+	// Suggestions are the keys that are similar To Key, closest first.
+	Suggestions []string
+	// sep is the key-value separator.
+	sep         string
+	// help lists all the possible keys of the KeyValueFlags.
+	help        string
+}
+
+func (e *UnknownKeyError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s details: cannot find key in text: \"%s\"%s, keys must be one or more character long followed by a '%s' then an optional whitespace\nany one of the following keys are valid (case insensitive):\n%s", e.Type, e.Input, column(e.Offset), e.sep, e.help)
+	}
+	s := fmt.Sprintf("%s details: unknown key \"%s\" in \"%s\"%s", e.Type, e.Key, e.Input, column(e.Offset))
+	if len(e.Suggestions) > 0 {
+		s += fmt.Sprintf(", did you mean \"%s\"?", strings.Join(e.Suggestions, "\" or \""))
+	}
+	return s
+}
+
+// DuplicateKeyError is returned when a field of a KeyValueFlags is given by more than one key-value pair.
+type DuplicateKeyError struct {
+	// Type is the name of the KeyValueFlags.
+	Type   string
+	// Field is the name of the field that is given more than once.
+	Field  string
+	// Input is the key-value pair that gives the field again.
+	Input  string
+	// Offset is the byte offset of Input within the value that was being parsed, or -1 if it is not known.
+	Offset int
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("%s details: specified the same %s field multiple times in list of key-value pairs, it is given again by \"%s\"%s", e.Type, e.Field, e.Input, column(e.Offset))
+}
+
+// MissingFieldsError is returned when any of the required fields of a KeyValueFlags are not given.
+type MissingFieldsError struct {
+	// Type is the name of the KeyValueFlags.
+	Type   string
+	// Fields are the names of the required fields that were not given.
+	Fields []string
+	// Input is the value that the fields are missing From, which is empty if it is not known.
+	Input  string
+	// Offset is the byte offset of Input within the value that was being parsed, or -1 if it is not known.
+	Offset int
+	// keys are the keys of each of the Fields.
+	keys   [][]string
+}
+
+func (e *MissingFieldsError) Error() string {
+	var missing strings.Builder
+	for i, field := range e.Fields {
+		missing.WriteString("\t- " + field + " (" + strings.Join(e.keys[i], ", ") + ")\n")
+	}
+	in := ""
+	if e.Input != "" {
+		in = fmt.Sprintf(" in \"%s\"%s", e.Input, column(e.Offset))
+	}
+	return fmt.Sprintf("%s details: you need To give a key-value pair for each of the following required fields%s:\n%s", e.Type, in, missing.String())
+}
+
+// ValidationError is returned when the value of a key-value pair cannot be set, or is not valid, for its field. Err
+// is the reason why, and can be unwrapped using errors.As (e.g. To a MoneyParseError).
+type ValidationError struct {
+	// Type is the name of the KeyValueFlags.
+	Type   string
+	// Field is the name of the field.
+	Field  string
+	// Input is the value of the key-value pair.
+	Input  string
+	// Offset is the byte offset of Input within the value that was being parsed, or -1 if it is not known.
+	Offset int
+	// Err is the reason that the value is not valid.
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s details: invalid %s \"%s\"%s: %s", e.Type, e.Field, e.Input, column(e.Offset), e.Err.Error())
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// positionedError is implemented by the errors that contain the Offset of their Input.
+type positionedError interface {
+	error
+	shift(offset int)
+}
+
+func (e *UnknownKeyError) shift(offset int) {
+	e.Offset = shiftOffset(e.Offset, offset)
+}
+
+func (e *DuplicateKeyError) shift(offset int) {
+	e.Offset = shiftOffset(e.Offset, offset)
+}
+
+func (e *MissingFieldsError) shift(offset int) {
+	e.Offset = shiftOffset(e.Offset, offset)
+}
+
+func (e *ValidationError) shift(offset int) {
+	e.Offset = shiftOffset(e.Offset, offset)
+}
+
+// shiftOffset adds the given offset To the Offset of an error. The Offset is not known if either is negative.
+func shiftOffset(errOffset int, offset int) int {
+	if errOffset < 0 || offset < 0 {
+		return -1
+	}
+	return errOffset + offset
+}
+
+// shiftError adds the given offset To the Offset of the given error, if it is a positionedError. This is used when the
+// value that the error occurred in is part of a larger value (e.g. an Item within Items).
+func shiftError(err error, offset int) error {
+	var positioned positionedError
+	if errors.As(err, &positioned) {
+		positioned.shift(offset)
+	}
+	return err
+}
+
+// column returns the column of the given Offset within an error message, or nothing if the Offset is not known.
+func column(offset int) string {
+	if offset < 0 {
+		return ""
+	}
+	return fmt.Sprintf(" at column %d", offset + 1)
+}
+
+// suggestKeys returns up To 3 of the given keys that are closest To the given unknown key, closest first. Keys are
+// only suggested if they can be reached by at most a third of the length of the unknown key in edits.
+func suggestKeys(key string, keys []string) []string {
+	maxDistance := len(key) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	distances := make(map[string]int)
+	suggestions := make([]string, 0)
+	for _, possible := range keys {
+		if _, ok := distances[possible]; ok {
+			continue
+		}
+		distance := editDistance(key, possible)
+		if distance <= maxDistance && distance < len(key) {
+			distances[possible] = distance
+			suggestions = append(suggestions, possible)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+	return suggestions
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions of adjacent runes needed
+// To turn a into b (the optimal string alignment distance).
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra) + 1)
+	for i := range d {
+		d[i] = make([]int, len(rb) + 1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i - 1] == rb[j - 1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i - 1][j] + 1, d[i][j - 1] + 1, d[i - 1][j - 1] + cost)
+			if i > 1 && j > 1 && ra[i - 1] == rb[j - 2] && ra[i - 2] == rb[j - 1] {
+				d[i][j] = minInt(d[i][j], d[i - 2][j - 2] + 1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(first int, rest ...int) int {
+	for _, i := range rest {
+		if i < first {
+			first = i
+		}
+	}
+	return first
+}
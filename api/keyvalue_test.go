@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"github.com/andygello555/ginvoice/globals"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// keyValueTest is a KeyValueFlags used To test the struct tags that keyValLogic and setKeyVals are driven by.
+type keyValueTest struct {
+	Name    string   `kv:"name,n" required:"true"`
+	Count   uint     `kv:"count,c" default:"3"`
+	Enabled bool     `kv:"enabled,on"`
+	Email   string   `kv:"email,e" validate:"email"`
+	Tags    []string `kv:"tags,t"`
+	Ignored string
+}
+
+func (k *keyValueTest) KeyVal(keyVal string, config *ParserConfig) (interface{}, error) {
+	return keyValLogic(keyVal, k, config, config.Separators.SecondLevelSplit())
+}
+
+func (k *keyValueTest) Set(value string) error {
+	return setLogic(value, k, DefaultParserConfig, DefaultParserConfig.Separators.FirstLevelSplit())
+}
+
+func TestSetKeyVals(t *testing.T) {
+	for _, test := range []struct{
+		input  string
+		config *ParserConfig
+		err    error
+		out    keyValueTest
+	}{
+		{
+			input: "name: Thing, count: 5, on: true, e: john@example.com, tags: a;b",
+			out:   keyValueTest{Name: "Thing", Count: 5, Enabled: true, Email: "john@example.com", Tags: []string{"a", "b"}},
+		},
+		{
+			input: "N: Thing",
+			out:   keyValueTest{Name: "Thing", Count: 3},
+		},
+		{
+			input: "count: 5",
+			err:   errors.New("keyValueTest details: you need To give a key-value pair for each of the following required fields in \"count: 5\" at column 1:\n\t- Name (name, n)\n"),
+		},
+		{
+			input: "name: Thing, email: not an email",
+			err:   errors.New("keyValueTest details: invalid Email \"not an email\" at column 21: \"not an email\" is not a valid email"),
+		},
+		{
+			input: "name: Thing, n: Other thing",
+			err:   errors.New("keyValueTest details: specified the same Name field multiple times in list of key-value pairs, it is given again by \"n: Other thing\" at column 14"),
+		},
+		{
+			input: "name: Thing, ignored: value",
+			err:   errors.New("keyValueTest details: unknown key \"ignored\" in \"ignored: value\" at column 14"),
+		},
+		{
+			input: "name: Thing, emial: john@example.com",
+			err:   errors.New("keyValueTest details: unknown key \"emial\" in \"emial: john@example.com\" at column 14, did you mean \"email\"?"),
+		},
+		{
+			input: "name: Thing, count: many",
+			err:   errors.New("invalid syntax"),
+		},
+		{
+			input:  "name= 1.234,50; tags= a/b; on= true",
+			config: &ParserConfig{Separators: globals.Separators{FirstLevel: ";", SecondLevel: "/", ThirdLevel: "|", KeyValue: "="}},
+			out:    keyValueTest{Name: "1.234,50", Count: 3, Enabled: true, Tags: []string{"a", "b"}},
+		},
+		{
+			input:  "name: Thing",
+			config: &ParserConfig{Separators: globals.Separators{FirstLevel: ";", SecondLevel: "/", ThirdLevel: "|", KeyValue: "="}},
+			err:    errors.New("keys must be one or more character long followed by a '=' then an optional whitespace"),
+		},
+		{
+			input: "name Thing",
+			err:   errors.New("\t- The following are all possible prefixes for \"Name\"\n\t\t- name\n\t\t- n\n\t- The following are all possible prefixes for \"Count\"\n\t\t- count\n\t\t- c\n"),
+		},
+	} {
+		config := test.config
+		if config == nil {
+			config = &ParserConfig{Separators: globals.DefaultSeparators()}
+		}
+		var out keyValueTest
+		err := setLogic(test.input, &out, config, config.Separators.FirstLevelSplit())
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("setting key-value pairs \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("setting key-value pairs \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("setting key-value pairs \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else {
+			if !reflect.DeepEqual(out, test.out) {
+				t.Errorf("expected output (%v) does not match actual output: %v", test.out, out)
+			}
+		}
+	}
+}
+
+func TestKeyValueErrors(t *testing.T) {
+	t.Run("UnknownKeyError", func(t *testing.T) {
+		var out keyValueTest
+		err := out.Set("name: Thing, tgas: a;b")
+		var unknownErr *UnknownKeyError
+		if !errors.As(err, &unknownErr) {
+			t.Fatalf("expected an UnknownKeyError, got: %v", err)
+		}
+		if unknownErr.Key != "tgas" || unknownErr.Input != "tgas: a;b" || unknownErr.Offset != 13 || !reflect.DeepEqual(unknownErr.Suggestions, []string{"tags"}) {
+			t.Errorf("UnknownKeyError has the incorrect fields: %+v", *unknownErr)
+		}
+	})
+
+	t.Run("DuplicateKeyError", func(t *testing.T) {
+		var out keyValueTest
+		err := out.Set("name: Thing, c: 1, count: 2")
+		var duplicateErr *DuplicateKeyError
+		if !errors.As(err, &duplicateErr) {
+			t.Fatalf("expected a DuplicateKeyError, got: %v", err)
+		}
+		if duplicateErr.Field != "Count" || duplicateErr.Input != "count: 2" || duplicateErr.Offset != 19 {
+			t.Errorf("DuplicateKeyError has the incorrect fields: %+v", *duplicateErr)
+		}
+	})
+
+	t.Run("MissingFieldsError", func(t *testing.T) {
+		var out Bank
+		err := out.Set("bank: Barclays")
+		var missingErr *MissingFieldsError
+		if !errors.As(err, &missingErr) {
+			t.Fatalf("expected a MissingFieldsError, got: %v", err)
+		}
+		if missingErr.Type != "Bank" || !reflect.DeepEqual(missingErr.Fields, []string{"AccountNo", "SortCode"}) || missingErr.Input != "bank: Barclays" || missingErr.Offset != 0 {
+			t.Errorf("MissingFieldsError has the incorrect fields: %+v", *missingErr)
+		}
+
+		// The Offset of the item that is missing fields is relative To the start of the Items
+		items := make(Items, 0)
+		err = items.Set("d: Did thing; r: GBP 60.00, r: GBP 5")
+		if !errors.As(err, &missingErr) {
+			t.Fatalf("expected a MissingFieldsError, got: %v", err)
+		}
+		if missingErr.Type != "Item" || missingErr.Input != "r: GBP 5" || missingErr.Offset != 28 {
+			t.Errorf("MissingFieldsError has the incorrect fields: %+v", *missingErr)
+		}
+	})
+
+	t.Run("ValidationError", func(t *testing.T) {
+		items := make(Items, 0)
+		err := items.Set("d: Did thing; r: GBP 60.00, d: Other thing; r: GBP 1.2.3")
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected a ValidationError, got: %v", err)
+		}
+		if validationErr.Type != "Item" || validationErr.Field != "Rate" || validationErr.Input != "GBP 1.2.3" || validationErr.Offset != 47 {
+			t.Errorf("ValidationError has the incorrect fields: %+v", *validationErr)
+		}
+		var moneyErr *MoneyParseError
+		if !errors.As(err, &moneyErr) {
+			t.Errorf("expected the ValidationError To wrap a MoneyParseError, got: %v", validationErr.Err)
+		}
+	})
+
+	t.Run("DecodeInvoice", func(t *testing.T) {
+		_, err := DecodeInvoice(strings.NewReader("items:\n  - description: Did thing\n    rate: GBP 60.00\n    quantiy: 2\n"), DocumentYAML)
+		var unknownErr *UnknownKeyError
+		if !errors.As(err, &unknownErr) {
+			t.Fatalf("expected an UnknownKeyError, got: %v", err)
+		}
+		if unknownErr.Key != "quantiy" || unknownErr.Offset != -1 || !reflect.DeepEqual(unknownErr.Suggestions, []string{"quantity"}) {
+			t.Errorf("UnknownKeyError has the incorrect fields: %+v", *unknownErr)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Locale determines how Money is formatted and parsed.
+type Locale struct {
+	// Name is the name of the Locale (e.g. "de-DE").
+	Name          string
+	// Decimal is the separator between the major and minor units.
+	Decimal       string
+	// Group is the separator between each group of 3 digits in the major units. No grouping takes place if this is
+	// empty. A no-break space is used instead of a space so that amounts are never wrapped over multiple lines.
+	Group         string
+	// CurrencyAfter is whether the currency's symbol or abbreviation is placed after the amount.
+	CurrencyAfter bool
+	// CurrencySpace is whether there is a space between the currency's symbol and the amount. There is always a space
+	// between a currency's abbreviation and the amount.
+	CurrencySpace bool
+	// UseSymbol is whether Format uses the currency's symbol rather than its abbreviation.
+	UseSymbol     bool
+}
+
+var (
+	// Locales (const): all the predefined Locale(s) mapped by their lowercase name.
+	Locales = map[string]Locale{
+		"default": {"default", ".", "", false, false, false},
+		"en-gb":   {"en-GB", ".", ",", false, false, true},
+		"en-us":   {"en-US", ".", ",", false, false, true},
+		"de-de":   {"de-DE", ",", ".", true, true, true},
+		"de-at":   {"de-AT", ",", ".", false, true, true},
+		"de-ch":   {"de-CH", ".", "'", false, true, false},
+		"fr-fr":   {"fr-FR", ",", "\u00a0", true, true, true},
+		"fr-ch":   {"fr-CH", ",", "\u00a0", true, true, false},
+		"es-es":   {"es-ES", ",", ".", true, true, true},
+		"it-it":   {"it-IT", ",", ".", true, true, true},
+		"nl-nl":   {"nl-NL", ",", ".", false, true, true},
+	}
+)
+
+// DefaultLocale returns a copy of the "default" Locale, which is used by ParseMoney, the String methods of Money and
+// Invoice(s) without a Locale. It formats Money as "GBP 1234.56" and "£1234.56". Other Locale(s) are given explicitly
+// (e.g. using WithLocale or the Locale of a ParserConfig).
+func DefaultLocale() *Locale {
+	return LocaleFromName("default")
+}
+
+// LocaleFromName returns a copy of the predefined Locale with the given (case insensitive) name, or nil if there is no
+// such Locale. Names can be given with either a hyphen or an underscore (e.g. "de-DE" or "de_DE").
+func LocaleFromName(name string) *Locale {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+	if locale, ok := Locales[name]; ok {
+		return &locale
+	}
+	return nil
+}
+
+// Amount returns the formatted amount of Money without any currency information, using the Locale's separators (e.g.
+// "-1.234,56").
+func (l *Locale) Amount(m *Money) string {
+	digits := m.amount()
+	major, minor := digits, ""
+	if i := strings.Index(digits, "."); i != -1 {
+		major, minor = digits[:i], digits[i + 1:]
+	}
+
+	var b strings.Builder
+	b.WriteString(m.sign())
+	for i, digit := range major {
+		if i > 0 && l.Group != "" && (len(major) - i) % 3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(digit)
+	}
+	if minor != "" {
+		b.WriteString(l.Decimal)
+		b.WriteString(minor)
+	}
+	return b.String()
+}
+
+// Quantity returns the formatted Quantity using the Locale's decimal separator (e.g. "7,5").
+func (l *Locale) Quantity(q *Quantity) string {
+	return q.decimal(l.Decimal)
+}
+
+// withCurrency places the given currency string before or after the formatted amount of Money.
+func (l *Locale) withCurrency(m *Money, currency string, space bool) string {
+	amount := l.Amount(m)
+	separator := ""
+	if space {
+		separator = " "
+	}
+	if l.CurrencyAfter {
+		return amount + separator + currency
+	}
+	// If the currency is not separated From the amount then the sign goes before the currency (e.g. "-£10.00" rather
+	// than "£-10.00")
+	if !space {
+		return m.sign() + currency + strings.TrimPrefix(amount, "-")
+	}
+	return currency + separator + amount
+}
+
+// Symbol returns the formatted Money using the currency's symbol (e.g. "1.234,56 €"). If the currency does not have a
+// symbol then its abbreviation is used instead.
+func (l *Locale) Symbol(m *Money) string {
+	if m.Currency == ZeroCurrency {
+		return ""
+	}
+	if m.Currency.Symbol == "" {
+		return l.Abbr(m)
+	}
+	return l.withCurrency(m, m.Currency.Symbol, l.CurrencySpace)
+}
+
+// Abbr returns the formatted Money using the currency's abbreviation (e.g. "1.234,56 EUR").
+func (l *Locale) Abbr(m *Money) string {
+	if m.Currency == ZeroCurrency {
+		return ""
+	}
+	return l.withCurrency(m, m.Currency.Abbr, true)
+}
+
+// Format returns the formatted Money using either the currency's symbol or abbreviation depending on UseSymbol.
+func (l *Locale) Format(m *Money) string {
+	if l.UseSymbol {
+		return l.Symbol(m)
+	}
+	return l.Abbr(m)
+}
+
+func (l *Locale) String() string {
+	return l.Name
+}
+
+// Set the Locale From the name of one of the predefined Locales.
+//
+// A valid string value can be:
+//  de-DE
+func (l *Locale) Set(value string) error {
+	locale := LocaleFromName(value)
+	if locale == nil {
+		names := make([]string, 0, len(Locales))
+		for _, locale := range Locales {
+			names = append(names, locale.Name)
+		}
+		sort.Strings(names)
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid locale, valid locales are: %s", value, strings.Join(names, ", ")))
+	}
+	*l = *locale
+	return nil
+}
@@ -3,180 +3,297 @@ package api
 import (
 	"errors"
 	"fmt"
-	str "github.com/andygello555/gotils/strings"
-	"regexp"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
-	"unicode/utf8"
 )
 
+// Currency represents a single ISO 4217 currency.
 type Currency struct {
-	Abbr   string
-	Symbol string
+	// Abbr is the alphabetic code of the currency (e.g. "GBP").
+	Abbr     string
+	// Numeric is the numeric code of the currency (e.g. 826).
+	Numeric  uint16
+	// Symbol is the symbol of the currency (e.g. "£"). This can be empty if the currency has no unique symbol.
+	Symbol   string
+	// Exponent is the number of digits after the decimal separator (i.e. the minor unit).
+	Exponent int
+	// Name is the English name of the currency.
+	Name     string
 }
 
 var (
 	// GreatBritishPound (const)
-	GreatBritishPound = Currency{"GBP", "£"}
+	GreatBritishPound  = Currency{"GBP", 826, "£", 2, "Pound Sterling"}
 	// UnitedStatesDollar (const)
-	UnitedStatesDollar = Currency{"USD", "$"}
+	UnitedStatesDollar = Currency{"USD", 840, "$", 2, "US Dollar"}
+	// Euro (const)
+	Euro               = Currency{"EUR", 978, "€", 2, "Euro"}
+	// JapaneseYen (const)
+	JapaneseYen        = Currency{"JPY", 392, "¥", 0, "Yen"}
+	// SwissFranc (const)
+	SwissFranc         = Currency{"CHF", 756, "", 2, "Swiss Franc"}
+	// KuwaitiDinar (const)
+	KuwaitiDinar       = Currency{"KWD", 414, "", 3, "Kuwaiti Dinar"}
 	// ZeroCurrency (const): An empty Currency To use as a default
 	ZeroCurrency       = Currency{}
-	// Currencies (const)
-	Currencies = map[*Currency]struct{}{
-		&GreatBritishPound:  {},
-		&UnitedStatesDollar: {},
-		&ZeroCurrency:       {},
-	}
-	CheckIfMoney = regexp.MustCompile("([A-Z]{3} ?|^\\W)\\d+\\.?\\d*")
+	// Currencies (const): all ISO 4217 currencies mapped by their abbreviation
+	Currencies         = make(map[string]*Currency)
+	// currenciesBySymbol (const): all currencies that have a symbol mapped by their symbol
+	currenciesBySymbol = make(map[string]*Currency)
 )
 
-func CurrencyFromSymbol(symbol string) *Currency {
-	for currency := range Currencies {
-		if currency.Symbol == symbol {
-			return currency
+func init() {
+	for _, currency := range currencyTable {
+		Currencies[currency.Abbr] = currency
+		if currency.Symbol != "" {
+			currenciesBySymbol[currency.Symbol] = currency
 		}
 	}
-	return nil
 }
 
+// CurrencyFromSymbol returns the Currency with the given symbol, or nil if there is no such Currency.
+func CurrencyFromSymbol(symbol string) *Currency {
+	return currenciesBySymbol[symbol]
+}
+
+// CurrencyFromAbbr returns the Currency with the given ISO 4217 abbreviation, or nil if there is no such Currency.
 func CurrencyFromAbbr(abbr string) *Currency {
-	for currency := range Currencies {
-		if currency.Abbr == abbr {
-			return currency
+	return Currencies[strings.ToUpper(abbr)]
+}
+
+// Set the Currency From the given ISO 4217 abbreviation or symbol.
+//
+// A valid string value can be:
+//  GBP
+// Or:
+//  £
+func (c *Currency) Set(value string) error {
+	value = strings.TrimSpace(value)
+	currency := CurrencyFromSymbol(value)
+	if currency == nil {
+		if currency = CurrencyFromAbbr(value); currency == nil {
+			return errors.New(fmt.Sprintf("no currency with symbol/abbreviation: %s", value))
 		}
 	}
+	*c = *currency
 	return nil
 }
 
+func (c *Currency) String() string {
+	return c.Abbr
+}
+
+// minorUnits returns the number of minor units within a single major unit of the Currency (i.e. 10^Exponent).
+func (c Currency) minorUnits() int64 {
+	units := int64(1)
+	for i := 0; i < c.Exponent; i++ {
+		units *= 10
+	}
+	return units
+}
+
+// Money represents a signed amount of money in the minor units of its Currency (e.g. pence for GBP). Negative amounts
+// are used for discounts, refunds and credit notes. All arithmetic on Money is carried out exactly in minor units,
+// rounding only takes place in Mul and when converting From a decimal.
 type Money struct {
-	Money    uint64
+	Money    int64
 	Currency Currency
 }
 
 // ToMoney converts a float64 To Money
 // e.g. 1.23 To 1.23, 1.345 To 1.35 depending on what Currency is given.
+//
+// The float64 is first converted To its shortest decimal representation so that values such as 1.345, which cannot
+// be represented exactly as a float64, are rounded as they are written. This will panic if the float64 is not finite
+// or is too large To be represented as Money.
 func ToMoney(f float64, currency Currency) *Money {
-	var m uint64
-	switch currency {
-	case ZeroCurrency: fallthrough
-	case GreatBritishPound: fallthrough
-	case UnitedStatesDollar:
-		m = uint64((f * 100) + 0.5)
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		panic(errors.New(fmt.Sprintf("%v cannot be converted To Money", f)))
+	}
+	m, err := ratToMoney(r, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// ratToMoney converts the given rational amount of major units To Money, rounding halves up To the nearest minor
+// unit. An error is returned if the amount is too large To be represented as Money.
+func ratToMoney(r *big.Rat, currency Currency) (*Money, error) {
+	return roundMoney(new(big.Rat).Mul(r, new(big.Rat).SetInt64(currency.minorUnits())), RoundHalfUp, currency)
+}
+
+// roundMoney rounds the given rational amount of minor units To Money using the given RoundingMode. An error is
+// returned if the rounded amount does not fit in the int64 that Money is stored in, rather than letting it wrap. The
+// smallest int64 is also not allowed so that every Money value can be negated.
+func roundMoney(r *big.Rat, mode RoundingMode, currency Currency) (*Money, error) {
+	n := mode.Round(r)
+	if !n.IsInt64() || n.Int64() == math.MinInt64 {
+		major := new(big.Rat).Quo(r, new(big.Rat).SetInt64(currency.minorUnits()))
+		return nil, errors.New(fmt.Sprintf("%s %s is too large To be represented as Money", currency.Abbr, major.FloatString(currency.Exponent)))
+	}
+	return &Money{
+		Money:    n.Int64(),
+		Currency: currency,
+	}, nil
+}
+
+// Float64 converts Money To float64. This should only be used for display purposes as the result may not be exact.
+func (m *Money) Float64() float64 {
+	return float64(m.Money) / float64(m.Currency.minorUnits())
+}
+
+// Rat converts Money To an exact rational number of major units.
+func (m *Money) Rat() *big.Rat {
+	return big.NewRat(m.Money, m.Currency.minorUnits())
+}
+
+// IsZero returns whether the Money value is zero.
+func (m *Money) IsZero() bool {
+	return m.Money == 0
+}
+
+// IsNegative returns whether the Money value is less than zero.
+func (m *Money) IsNegative() bool {
+	return m.Money < 0
+}
+
+// Neg returns the Money value with its sign flipped.
+func (m *Money) Neg() *Money {
+	return &Money{-m.Money, m.Currency}
+}
+
+// Abs returns the absolute Money value.
+func (m *Money) Abs() *Money {
+	if m.IsNegative() {
+		return m.Neg()
+	}
+	return &Money{m.Money, m.Currency}
+}
+
+// commonCurrency returns the Currency that the result of an operation on both Money values should have. ZeroCurrency
+// is compatible with every other Currency, otherwise an error is returned if the currencies differ.
+func (m *Money) commonCurrency(o *Money) (Currency, error) {
+	switch {
+	case m.Currency == o.Currency || o.Currency == ZeroCurrency:
+		return m.Currency, nil
+	case m.Currency == ZeroCurrency:
+		return o.Currency, nil
 	default:
-		panic(errors.New(fmt.Sprintf("unsupported Currency: %s", currency.Abbr)))
+		return ZeroCurrency, errors.New(fmt.Sprintf("mismatched currencies: %s and %s", m.Currency.Abbr, o.Currency.Abbr))
+	}
+}
+
+// Add the given Money To the Money value. This will panic if the currencies of the two values differ (unless one is
+// ZeroCurrency).
+func (m *Money) Add(o *Money) *Money {
+	currency, err := m.commonCurrency(o)
+	if err != nil {
+		panic(err)
 	}
 	return &Money{
-		Money:    m,
+		Money:    m.Money + o.Money,
 		Currency: currency,
 	}
 }
 
-// ParseMoney parses a string To Money.
-//
-// The string can either be in the format:
-//  // Using the abbreviation
-//  GBP 10.00
-// Or:
-//  // Using the symbol
-//  £10.00
-func ParseMoney(s string) (*Money, error) {
-	if CheckIfMoney.MatchString(s) {
-		symbolOrAbbr := ""
-		money := ""
-		for _, char := range s {
-			charStr := string(char)
-			switch {
-			case charStr == " ":
-				continue
-			case strings.Contains(str.Numeric + ".", charStr):
-				money += charStr
-			case strings.Contains(str.Alpha, charStr): fallthrough
-			default:
-				symbolOrAbbr += charStr
-			}
-		}
-		// We see if the given symbolOrAbbr is a valid currency
-		var currency *Currency
-		symbolOrAbbr = strings.ToUpper(symbolOrAbbr)
-		if currency = CurrencyFromSymbol(symbolOrAbbr); currency == nil {
-			if currency = CurrencyFromAbbr(symbolOrAbbr); currency == nil {
-				return nil, errors.New(fmt.Sprintf("no currency with symbol/abbreviation: %s", symbolOrAbbr))
-			}
-		}
-		// Then we'll try and parse the money as a float
-		f, err := strconv.ParseFloat(money, 64)
-		if err != nil {
-			return nil, err
-		}
-		return ToMoney(f, *currency), nil
+// Sub subtracts the given Money From the Money value. This will panic if the currencies of the two values differ
+// (unless one is ZeroCurrency).
+func (m *Money) Sub(o *Money) *Money {
+	currency, err := m.commonCurrency(o)
+	if err != nil {
+		panic(err)
+	}
+	return &Money{
+		Money:    m.Money - o.Money,
+		Currency: currency,
 	}
-	return nil, errors.New(fmt.Sprintf("\"%s\" does not contain a regex match", s))
 }
 
-// Float64 converts Money To float64
-func (m *Money) Float64() float64 {
-	x := float64(m.Money)
-	switch m.Currency {
-	case ZeroCurrency: fallthrough
-	case GreatBritishPound: fallthrough
-	case UnitedStatesDollar:
-		x = x / 100
-	default:
-		panic(errors.New(fmt.Sprintf("unsupported Currency: %s", m.Currency.Abbr)))
+// Mul multiplies the Money value by the given rational quantity, rounding halves of the exact product up To the
+// nearest minor unit.
+func (m *Money) Mul(q *big.Rat) *Money {
+	return m.MulRounded(q, RoundDefault)
+}
+
+// MulRounded multiplies the Money value by the given rational quantity, rounding the exact product To the nearest
+// minor unit using the given RoundingMode. This will panic if the product is too large To be represented as Money.
+func (m *Money) MulRounded(q *big.Rat, mode RoundingMode) *Money {
+	product, err := roundMoney(new(big.Rat).Mul(new(big.Rat).SetInt64(m.Money), q), mode, m.Currency)
+	if err != nil {
+		panic(err)
 	}
-	return x
+	return product
 }
 
 // Multiply safely multiplies a Money value by a float64, rounding
-// To the nearest cent.
+// To the nearest minor unit. The float64 is interpreted as its shortest decimal representation.
 func (m *Money) Multiply(f float64) *Money {
-	var x float64
-	switch m.Currency {
-	case ZeroCurrency: fallthrough
-	case GreatBritishPound: fallthrough
-	case UnitedStatesDollar:
-		x = (float64(m.Money) * f) + 0.5
+	q, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return m.Mul(q)
+}
+
+// Div divides the Money value into n equal parts, returning the value of each part and the remainder in minor units
+// that could not be divided evenly. Both are truncated towards zero, so the remainder has the same sign as the Money
+// value. This will panic if n is 0, or if the quotient is too large To be represented as Money.
+func (m *Money) Div(n int64) (quotient *Money, remainder *Money) {
+	if n == 0 {
+		panic(errors.New("cannot divide Money by zero"))
 	}
-	return &Money{
-		Money:    uint64(x),
-		Currency: m.Currency,
+	if n == -1 && m.Money == math.MinInt64 {
+		panic(errors.New(fmt.Sprintf("%s divided by -1 is too large To be represented as Money", m.StringAbbr())))
 	}
+	return &Money{m.Money / n, m.Currency}, &Money{m.Money % n, m.Currency}
 }
 
-// Add the given float64 To the Money value.
-func (m *Money) Add(f float64) *Money {
-	return ToMoney(m.Float64() + f, m.Currency)
+// amount returns the formatted absolute amount of Money without any currency information, using the number of decimal
+// places given by the currency's Exponent.
+func (m *Money) amount() string {
+	units := m.Currency.minorUnits()
+	abs := m.Abs().Money
+	if m.Currency.Exponent == 0 {
+		return strconv.FormatInt(abs, 10)
+	}
+	return fmt.Sprintf("%d.%0*d", abs / units, m.Currency.Exponent, abs % units)
 }
 
-// String returns a formatted Money value with the currency's symbol and its abbreviation.
-func (m *Money) String() string {
-	if m.Currency != ZeroCurrency {
-		x := float64(m.Money)
-		x = x / 100
-		return fmt.Sprintf("%s %s%.2f", m.Currency.Abbr, m.Currency.Symbol, x)
+// sign returns "-" if the Money value is negative, otherwise the empty string.
+func (m *Money) sign() string {
+	if m.IsNegative() {
+		return "-"
 	}
 	return ""
 }
 
-// StringSymbol returns a formatted Money value with the currency's symbol.
-func (m *Money) StringSymbol() string {
-	s := m.String()
-	if len(s) != 0 {
-		s = strings.Split(s, " ")[1]
+// String returns a formatted Money value with the currency's symbol and its abbreviation using the DefaultLocale.
+// Negative values are given with a minus sign before the symbol (e.g. "GBP -£10.00"). Use the methods of a Locale To
+// format Money in another Locale.
+func (m *Money) String() string {
+	if m.Currency == ZeroCurrency {
+		return ""
 	}
-	return s
+	if m.Currency.Symbol == "" {
+		return m.StringAbbr()
+	}
+	return m.Currency.Abbr + " " + m.StringSymbol()
+}
+
+// StringSymbol returns a formatted Money value with the currency's symbol using the DefaultLocale. If the currency
+// does not have a symbol then its abbreviation is used instead.
+func (m *Money) StringSymbol() string {
+	return DefaultLocale().Symbol(m)
 }
 
-// StringAbbr returns a formatted Money value with the currency's abbreviated type.
+// StringAbbr returns a formatted Money value with the currency's abbreviated type using the DefaultLocale.
 func (m *Money) StringAbbr() string {
-	s := m.String()
-	if len(s) != 0 {
-		sp := strings.Split(s, " ")
-		abbr := sp[0]
-		_, symI := utf8.DecodeRuneInString(sp[1])
-		money := sp[1][symI:]
-		s = fmt.Sprintf("%s %s", abbr, money)
-	}
-	return s
+	return DefaultLocale().Abbr(m)
+}
+
+// StringAmount returns the formatted amount of Money without any currency information using the DefaultLocale (e.g.
+// "-10.00").
+func (m *Money) StringAmount() string {
+	return DefaultLocale().Amount(m)
 }
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// MoneyParseError is returned when a string cannot be parsed as Money.
+type MoneyParseError struct {
+	// Input is the string that was being parsed.
+	Input  string
+	// Offset is the byte offset within Input at which the error occurred, like the Offset of a ValidationError.
+	Offset int
+	// Reason describes what went wrong.
+	Reason string
+}
+
+func (e *MoneyParseError) Error() string {
+	return fmt.Sprintf("cannot parse money \"%s\" at column %d: %s", e.Input, e.Offset + 1, e.Reason)
+}
+
+// moneyTokenKind is the kind of a moneyToken.
+type moneyTokenKind int
+
+const (
+	moneyNumber moneyTokenKind = iota
+	moneyCurrency
+	moneyMinus
+	moneyOpen
+	moneyClose
+)
+
+// moneyToken is a single token within a Money string.
+type moneyToken struct {
+	kind   moneyTokenKind
+	text   []rune
+	offset int
+}
+
+// isMoneyDigit returns whether the given rune is an ASCII digit.
+func isMoneyDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isMoneySpace returns whether the given rune is whitespace within a Money string.
+func isMoneySpace(r rune) bool {
+	return unicode.IsSpace(r) || r == '\u00a0' || r == '\u202f'
+}
+
+// isGroupSeparator returns whether the given rune can be used To group digits when parsing Money using the Locale.
+// Spaces can always be used. If the Locale does not group digits then whichever of "," and "." is not the Locale's
+// decimal separator can be used.
+func (l *Locale) isGroupSeparator(r rune) bool {
+	switch {
+	case isMoneySpace(r):
+		return true
+	case l.Group != "":
+		return string(r) == l.Group || l.Group == "'" && r == '\u2019'
+	default:
+		return (r == ',' || r == '.') && string(r) != l.Decimal
+	}
+}
+
+// tokenizeMoney splits the given Money string into moneyToken(s).
+func tokenizeMoney(input []rune, locale *Locale) ([]*moneyToken, *MoneyParseError) {
+	tokens := make([]*moneyToken, 0)
+	isDigit := func(i int) bool {
+		return i < len(input) && isMoneyDigit(input[i])
+	}
+	for i := 0; i < len(input); {
+		r := input[i]
+		switch {
+		case isMoneySpace(r):
+			i++
+		case r == '-' || r == '\u2212':
+			tokens = append(tokens, &moneyToken{moneyMinus, input[i:i + 1], i})
+			i++
+		case r == '(':
+			tokens = append(tokens, &moneyToken{moneyOpen, input[i:i + 1], i})
+			i++
+		case r == ')':
+			tokens = append(tokens, &moneyToken{moneyClose, input[i:i + 1], i})
+			i++
+		case isMoneyDigit(r):
+			// Numbers can contain decimal and group separators as long as they are followed by a digit
+			start := i
+			for i < len(input) {
+				if isDigit(i) || (string(input[i]) == locale.Decimal || locale.isGroupSeparator(input[i])) && isDigit(i + 1) {
+					i++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, &moneyToken{moneyNumber, input[start:i], start})
+		case unicode.IsLetter(r) || unicode.IsSymbol(r) || unicode.IsPunct(r):
+			start := i
+			for i < len(input) && !isMoneySpace(input[i]) && !isMoneyDigit(input[i]) && !strings.ContainsRune("-\u2212()", input[i]) {
+				i++
+			}
+			// Symbols can contain spaces (e.g. "F CFA") so we join currency tokens that are only separated by spaces
+			if n := len(tokens); n > 0 && tokens[n - 1].kind == moneyCurrency {
+				prev := tokens[n - 1]
+				prev.text = append(append(append([]rune{}, prev.text...), ' '), input[start:i]...)
+				continue
+			}
+			tokens = append(tokens, &moneyToken{moneyCurrency, input[start:i], start})
+		default:
+			return nil, &MoneyParseError{Offset: i, Reason: fmt.Sprintf("unexpected character '%c'", r)}
+		}
+	}
+	return tokens, nil
+}
+
+// parseMoneyNumber parses the text of a number token into a decimal string that can be read by big.Rat. The digit
+// grouping and the number of decimal places are validated against the given Currency.
+func parseMoneyNumber(token *moneyToken, locale *Locale, currency *Currency) (string, *MoneyParseError) {
+	var major, minor strings.Builder
+	decimal := -1
+	groupSeparator := rune(0)
+	groupStart := 0
+	groups := 0
+	for i, r := range token.text {
+		switch {
+		case isMoneyDigit(r):
+			if decimal == -1 {
+				major.WriteRune(r)
+			} else {
+				minor.WriteRune(r)
+				if minor.Len() > currency.Exponent {
+					return "", &MoneyParseError{Offset: token.offset + i, Reason: fmt.Sprintf("%s has %d decimal places but more were given", currency.Abbr, currency.Exponent)}
+				}
+			}
+		case string(r) == locale.Decimal && decimal == -1:
+			if groups > 0 && i - groupStart != 3 {
+				return "", &MoneyParseError{Offset: token.offset + groupStart, Reason: "each group of digits after the first must contain 3 digits"}
+			}
+			decimal = i
+		case locale.isGroupSeparator(r) && decimal == -1:
+			switch {
+			case groups == 0 && i > 3:
+				return "", &MoneyParseError{Offset: token.offset, Reason: "the first group of digits must contain between 1 and 3 digits"}
+			case groups > 0 && i - groupStart != 3:
+				return "", &MoneyParseError{Offset: token.offset + groupStart, Reason: "each group of digits after the first must contain 3 digits"}
+			case groups > 0 && r != groupSeparator:
+				return "", &MoneyParseError{Offset: token.offset + i, Reason: fmt.Sprintf("mixed group separators '%c' and '%c'", groupSeparator, r)}
+			}
+			groupSeparator = r
+			groupStart = i + 1
+			groups++
+		default:
+			return "", &MoneyParseError{Offset: token.offset + i, Reason: fmt.Sprintf("unexpected '%c' in number", r)}
+		}
+	}
+	if decimal == -1 && groups > 0 && len(token.text) - groupStart != 3 {
+		return "", &MoneyParseError{Offset: token.offset + groupStart, Reason: "each group of digits after the first must contain 3 digits"}
+	}
+	if minor.Len() > 0 {
+		return major.String() + "." + minor.String(), nil
+	}
+	return major.String(), nil
+}
+
+// ParseMoney parses a string To Money using the DefaultLocale. Use ParseMoneyLocale To parse Money in another Locale.
+//
+// The string can either be in the format:
+//  // Using the abbreviation
+//  GBP 10.00
+// Or:
+//  // Using the symbol
+//  £10.00
+// The currency can also be given after the number, and digits can be grouped:
+//  1,234.50 EUR
+//  10 €
+//  USD 1 000
+// Negative amounts can be given with a minus sign before either the currency or the number, or by wrapping the whole
+// amount in parentheses (accounting style):
+//  -GBP 10.00
+//  GBP -10.00
+//  (GBP 10.00)
+// Giving more decimal places than the currency has (e.g. "GBP 1.005") is an error.
+func ParseMoney(s string) (*Money, error) {
+	return ParseMoneyLocale(s, DefaultLocale())
+}
+
+// ParseMoneyLocale parses a string To Money using the decimal and group separators of the given Locale. For example,
+// using the "de-DE" Locale:
+//  1.234,56 €
+//  EUR 1.234,56
+// A *MoneyParseError is returned if the string cannot be parsed.
+func ParseMoneyLocale(s string, locale *Locale) (*Money, error) {
+	// The Money string is parsed as runes, so the Offset of each error is converted From a rune index To a byte offset
+	input := []rune(s)
+	fail := func(err *MoneyParseError) (*Money, error) {
+		err.Input = s
+		err.Offset = len(string(input[:err.Offset]))
+		return nil, err
+	}
+
+	tokens, err := tokenizeMoney(input, locale)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Unwrap accounting style parentheses
+	negative := false
+	if len(tokens) > 0 && tokens[0].kind == moneyOpen {
+		if tokens[len(tokens) - 1].kind != moneyClose {
+			return fail(&MoneyParseError{Offset: len(input), Reason: "missing closing parenthesis"})
+		}
+		negative = true
+		tokens = tokens[1:len(tokens) - 1]
+	}
+
+	var number, currencyToken *moneyToken
+	for _, token := range tokens {
+		switch token.kind {
+		case moneyMinus:
+			if negative {
+				return fail(&MoneyParseError{Offset: token.offset, Reason: "more than one negative sign"})
+			}
+			if number != nil {
+				return fail(&MoneyParseError{Offset: token.offset, Reason: "negative sign after the number"})
+			}
+			negative = true
+		case moneyNumber:
+			if number != nil {
+				return fail(&MoneyParseError{Offset: token.offset, Reason: "more than one number"})
+			}
+			number = token
+		case moneyCurrency:
+			if currencyToken != nil {
+				return fail(&MoneyParseError{Offset: token.offset, Reason: "more than one currency"})
+			}
+			currencyToken = token
+		default:
+			return fail(&MoneyParseError{Offset: token.offset, Reason: fmt.Sprintf("unexpected '%s'", string(token.text))})
+		}
+	}
+	if number == nil {
+		return fail(&MoneyParseError{Offset: len(input), Reason: "no amount given"})
+	}
+	if currencyToken == nil {
+		return fail(&MoneyParseError{Offset: len(input), Reason: "no currency given, currencies are given as a 3 letter abbreviation (e.g. GBP) or symbol (e.g. £)"})
+	}
+
+	// We see if the given symbol or abbreviation is a valid currency
+	symbolOrAbbr := string(currencyToken.text)
+	var currency *Currency
+	if currency = CurrencyFromSymbol(symbolOrAbbr); currency == nil {
+		if currency = CurrencyFromAbbr(symbolOrAbbr); currency == nil {
+			return fail(&MoneyParseError{Offset: currencyToken.offset, Reason: fmt.Sprintf("no currency with symbol/abbreviation: %s", symbolOrAbbr)})
+		}
+	}
+
+	// Then we'll parse the money as an exact decimal
+	decimal, err := parseMoneyNumber(number, locale, currency)
+	if err != nil {
+		return fail(err)
+	}
+	r, _ := new(big.Rat).SetString(decimal)
+	if negative {
+		r.Neg(r)
+	}
+	m, rangeErr := ratToMoney(r, *currency)
+	if rangeErr != nil {
+		return fail(&MoneyParseError{Offset: number.offset, Reason: rangeErr.Error()})
+	}
+	return m, nil
+}
@@ -0,0 +1,609 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestCurrencies(t *testing.T) {
+	for _, test := range []struct{
+		abbr     string
+		symbol   string
+		numeric  uint16
+		exponent int
+	}{
+		{"GBP", "£", 826, 2},
+		{"USD", "$", 840, 2},
+		{"EUR", "€", 978, 2},
+		{"JPY", "¥", 392, 0},
+		{"CHF", "", 756, 2},
+		{"KWD", "", 414, 3},
+		{"CLF", "", 990, 4},
+	} {
+		currency := CurrencyFromAbbr(test.abbr)
+		if currency == nil {
+			t.Errorf("cannot find currency with abbreviation \"%s\"", test.abbr)
+			continue
+		}
+		if currency.Numeric != test.numeric {
+			t.Errorf("currency \"%s\" has numeric code %03d, expected %03d", test.abbr, currency.Numeric, test.numeric)
+		}
+		if currency.Exponent != test.exponent {
+			t.Errorf("currency \"%s\" has exponent %d, expected %d", test.abbr, currency.Exponent, test.exponent)
+		}
+		if test.symbol != "" && CurrencyFromSymbol(test.symbol) != currency {
+			t.Errorf("currency with symbol \"%s\" is not \"%s\"", test.symbol, test.abbr)
+		}
+	}
+
+	if CurrencyFromSymbol("") != nil {
+		t.Errorf("the empty symbol should not match any currency")
+	}
+	if len(Currencies) != len(currencyTable) {
+		t.Errorf("there are %d currencies with duplicate abbreviations", len(currencyTable) - len(Currencies))
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	for _, test := range []struct{
+		input  string
+		err    error
+		out    Money
+		str    string
+		abbr   string
+		symbol string
+	}{
+		{
+			input:  "GBP 10.00",
+			out:    Money{1000, GreatBritishPound},
+			str:    "GBP £10.00",
+			abbr:   "GBP 10.00",
+			symbol: "£10.00",
+		},
+		{
+			input:  "€1234.5",
+			out:    Money{123450, Euro},
+			str:    "EUR €1234.50",
+			abbr:   "EUR 1234.50",
+			symbol: "€1234.50",
+		},
+		{
+			input:  "JPY 1500",
+			out:    Money{1500, JapaneseYen},
+			str:    "JPY ¥1500",
+			abbr:   "JPY 1500",
+			symbol: "¥1500",
+		},
+		{
+			input:  "chf 99.95",
+			out:    Money{9995, SwissFranc},
+			str:    "CHF 99.95",
+			abbr:   "CHF 99.95",
+			symbol: "CHF 99.95",
+		},
+		{
+			input:  "KWD 1.005",
+			out:    Money{1005, KuwaitiDinar},
+			str:    "KWD 1.005",
+			abbr:   "KWD 1.005",
+			symbol: "KWD 1.005",
+		},
+		{
+			input:  "-GBP 10.00",
+			out:    Money{-1000, GreatBritishPound},
+			str:    "GBP -£10.00",
+			abbr:   "GBP -10.00",
+			symbol: "-£10.00",
+		},
+		{
+			input:  "GBP -10.5",
+			out:    Money{-1050, GreatBritishPound},
+			str:    "GBP -£10.50",
+			abbr:   "GBP -10.50",
+			symbol: "-£10.50",
+		},
+		{
+			input:  "(GBP 10.00)",
+			out:    Money{-1000, GreatBritishPound},
+			str:    "GBP -£10.00",
+			abbr:   "GBP -10.00",
+			symbol: "-£10.00",
+		},
+		{
+			input:  "-¥300",
+			out:    Money{-300, JapaneseYen},
+			str:    "JPY -¥300",
+			abbr:   "JPY -300",
+			symbol: "-¥300",
+		},
+		{
+			input: "(GBP -10.00)",
+			err:   errors.New("cannot parse money \"(GBP -10.00)\" at column 6: more than one negative sign"),
+		},
+		{
+			input: "ABC 10.00",
+			err:   errors.New("no currency with symbol/abbreviation: ABC"),
+		},
+		{
+			input: "10.00",
+			err:   errors.New("cannot parse money \"10.00\" at column 6: no currency given"),
+		},
+		{
+			input:  "£1,234.50",
+			out:    Money{123450, GreatBritishPound},
+			str:    "GBP £1234.50",
+			abbr:   "GBP 1234.50",
+			symbol: "£1234.50",
+		},
+		{
+			input:  "1234.50 EUR",
+			out:    Money{123450, Euro},
+			str:    "EUR €1234.50",
+			abbr:   "EUR 1234.50",
+			symbol: "€1234.50",
+		},
+		{
+			input:  "10 €",
+			out:    Money{1000, Euro},
+			str:    "EUR €10.00",
+			abbr:   "EUR 10.00",
+			symbol: "€10.00",
+		},
+		{
+			input:  "USD 1 000",
+			out:    Money{100000, UnitedStatesDollar},
+			str:    "USD $1000.00",
+			abbr:   "USD 1000.00",
+			symbol: "$1000.00",
+		},
+		{
+			input:  "-1,000,000 F CFA",
+			out:    Money{-1000000, Currency{"XOF", 952, "F CFA", 0, "CFA Franc BCEAO"}},
+			str:    "XOF -F CFA1000000",
+			abbr:   "XOF -1000000",
+			symbol: "-F CFA1000000",
+		},
+		{
+			input: "GBP 1.005",
+			err:   errors.New("cannot parse money \"GBP 1.005\" at column 9: GBP has 2 decimal places but more were given"),
+		},
+		{
+			input: "JPY 10.5",
+			err:   errors.New("cannot parse money \"JPY 10.5\" at column 8: JPY has 0 decimal places but more were given"),
+		},
+		{
+			input: "£1,23.50",
+			err:   errors.New("cannot parse money \"£1,23.50\" at column 5: each group of digits after the first must contain 3 digits"),
+		},
+		{
+			input: "£1234,567",
+			err:   errors.New("cannot parse money \"£1234,567\" at column 3: the first group of digits must contain between 1 and 3 digits"),
+		},
+		{
+			input: "£1,234 567",
+			err:   errors.New("cannot parse money \"£1,234 567\" at column 8: mixed group separators ',' and ' '"),
+		},
+		{
+			input: "GBP 10 20",
+			err:   errors.New("cannot parse money \"GBP 10 20\" at column 8: each group of digits after the first must contain 3 digits"),
+		},
+		{
+			input: "GBP 10 -",
+			err:   errors.New("cannot parse money \"GBP 10 -\" at column 8: negative sign after the number"),
+		},
+		{
+			input: "GBP 10 £",
+			err:   errors.New("cannot parse money \"GBP 10 £\" at column 8: more than one currency"),
+		},
+		{
+			input: "(GBP 10",
+			err:   errors.New("cannot parse money \"(GBP 10\" at column 8: missing closing parenthesis"),
+		},
+		{
+			input: "GBP 99999999999999999999",
+			err:   errors.New("cannot parse money \"GBP 99999999999999999999\" at column 5: GBP 99999999999999999999.00 is too large To be represented as Money"),
+		},
+		{
+			input: "-GBP 92233720368547758.08",
+			err:   errors.New("GBP -92233720368547758.08 is too large To be represented as Money"),
+		},
+		{
+			input:  "-GBP 92233720368547758.07",
+			out:    Money{-math.MaxInt64, GreatBritishPound},
+			str:    "GBP -£92233720368547758.07",
+			abbr:   "GBP -92233720368547758.07",
+			symbol: "-£92233720368547758.07",
+		},
+	} {
+		money, err := ParseMoney(test.input)
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("parsing money \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("parsing money \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("parsing money \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else {
+			if *money != test.out {
+				t.Errorf("expected output (%v) does not match actual output: %v", &test.out, money)
+			}
+			if money.String() != test.str {
+				t.Errorf("expected String() output (%s) does not match actual output: %s", test.str, money.String())
+			}
+			if money.StringAbbr() != test.abbr {
+				t.Errorf("expected StringAbbr() output (%s) does not match actual output: %s", test.abbr, money.StringAbbr())
+			}
+			if money.StringSymbol() != test.symbol {
+				t.Errorf("expected StringSymbol() output (%s) does not match actual output: %s", test.symbol, money.StringSymbol())
+			}
+		}
+	}
+
+	// The Offset of a MoneyParseError is a byte offset, so the Input can be sliced at it even if it contains a symbol
+	var parseErr *MoneyParseError
+	if _, err := ParseMoney("€1,23.50"); !errors.As(err, &parseErr) || parseErr.Input[parseErr.Offset:] != "23.50" {
+		t.Errorf("parsing money \"€1,23.50\" should return a MoneyParseError at the byte offset of \"23.50\", got: %v", err)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	// Adding and then subtracting the same value should give back the original value
+	if err := quick.Check(func(a, b int32) bool {
+		x, y := &Money{int64(a), Euro}, &Money{int64(b), Euro}
+		return *x.Add(y).Sub(y) == *x
+	}, nil); err != nil {
+		t.Errorf("Add then Sub is not the identity: %v", err)
+	}
+
+	// Dividing should never lose a minor unit
+	if err := quick.Check(func(a int32, n uint8) bool {
+		x := &Money{int64(a), GreatBritishPound}
+		d := int64(n) + 1
+		q, r := x.Div(d)
+		return q.Money * d + r.Money == x.Money && r.Abs().Money < d
+	}, nil); err != nil {
+		t.Errorf("Div loses minor units: %v", err)
+	}
+
+	for _, test := range []struct{
+		money Money
+		qty   *big.Rat
+		out   Money
+	}{
+		{Money{1000, UnitedStatesDollar}, big.NewRat(3, 1), Money{3000, UnitedStatesDollar}},
+		{Money{1, GreatBritishPound}, big.NewRat(1, 2), Money{1, GreatBritishPound}},
+		{Money{333, GreatBritishPound}, big.NewRat(1, 3), Money{111, GreatBritishPound}},
+		{Money{1000, JapaneseYen}, big.NewRat(3, 4), Money{750, JapaneseYen}},
+		{Money{1005, KuwaitiDinar}, big.NewRat(1, 10), Money{101, KuwaitiDinar}},
+	} {
+		if out := test.money.Mul(test.qty); *out != test.out {
+			t.Errorf("%v × %s should be %v, got: %v", &test.money, test.qty.RatString(), &test.out, out)
+		}
+	}
+
+	// Floats are interpreted as they are written
+	if m := ToMoney(1.345, GreatBritishPound); m.Money != 135 {
+		t.Errorf("1.345 should be rounded To 135 minor units, got: %d", m.Money)
+	}
+
+	// Amounts that are too large To be represented as Money panic rather than wrapping around
+	for name, f := range map[string]func(){
+		"multiplying": func() { (&Money{math.MaxInt64, GreatBritishPound}).Mul(big.NewRat(2, 1)) },
+		"dividing":    func() { (&Money{math.MinInt64, GreatBritishPound}).Div(-1) },
+		"converting":  func() { ToMoney(1e300, GreatBritishPound) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s Money To an amount that is too large should panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestItemsTotalProperty(t *testing.T) {
+	// The total of any list of items should equal the sum of the subtotals as they are displayed
+	if err := quick.Check(func(rates []int32, hours []uint8, taxes []uint8) bool {
+		items := make(Items, 0)
+		for i, rate := range rates {
+			item := &Item{
+				Description:   "item",
+				HoursQuantity: NewQuantity(1),
+				Rate:          Money{int64(rate), KuwaitiDinar},
+			}
+			if i < len(hours) {
+				item.HoursQuantity = NewQuantity(int64(hours[i]))
+			}
+			if i < len(taxes) {
+				item.Tax = Taxes{{Rate: TaxRate{Percent: big.NewRat(int64(taxes[i]), 4)}}}
+			}
+			items = append(items, item)
+		}
+
+		displayed := &Money{0, ZeroCurrency}
+		for _, item := range items {
+			subtotal, err := ParseMoney(item.Subtotal().StringAbbr())
+			if err != nil {
+				return false
+			}
+			displayed = displayed.Add(subtotal)
+		}
+		total, err := items.Total()
+		return err == nil && total.StringAbbr() == displayed.StringAbbr()
+	}, nil); err != nil {
+		t.Errorf("Items total does not equal the sum of the displayed subtotals: %v", err)
+	}
+
+	// Items in different currencies cannot be totalled
+	items := Items{
+		{Description: "Did thing 1", HoursQuantity: NewQuantity(1), Rate: Money{1000, GreatBritishPound}},
+		{Description: "Did thing 2", HoursQuantity: NewQuantity(1), Rate: Money{1000, UnitedStatesDollar}},
+	}
+	if total, err := items.Total(); err == nil || !strings.Contains(err.Error(), "items have mixed currencies") {
+		t.Errorf("totalling Items in GBP and USD should return a mixed currencies error, got: %v, %v", total, err)
+	}
+}
+
+func TestRoundingMode(t *testing.T) {
+	for _, test := range []struct{
+		input string
+		mode  RoundingMode
+		// The expected results of rounding 2.5, 3.5, -2.5, 2.4 and 2.6
+		out   []int64
+	}{
+		{"half-up", RoundHalfUp, []int64{3, 4, -3, 2, 3}},
+		{"bankers", RoundHalfEven, []int64{2, 4, -2, 2, 3}},
+		{"half-down", RoundHalfDown, []int64{2, 3, -2, 2, 3}},
+		{"truncate", RoundDown, []int64{2, 3, -2, 2, 2}},
+		{"up", RoundUp, []int64{3, 4, -3, 3, 3}},
+	} {
+		var mode RoundingMode
+		if err := mode.Set(test.input); err != nil {
+			t.Errorf("parsing rounding mode \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		if mode != test.mode {
+			t.Errorf("rounding mode \"%s\" was parsed as %s", test.input, mode.String())
+		}
+		for i, x := range []*big.Rat{big.NewRat(5, 2), big.NewRat(7, 2), big.NewRat(-5, 2), big.NewRat(12, 5), big.NewRat(13, 5)} {
+			if out := mode.Round(x).Int64(); out != test.out[i] {
+				t.Errorf("rounding %s using %s should give %d, got: %d", x.RatString(), mode.String(), test.out[i], out)
+			}
+		}
+	}
+
+	var mode RoundingMode
+	if err := mode.Set("sideways"); err == nil {
+		t.Errorf("parsing rounding mode \"sideways\" should return an error")
+	}
+
+	// Unknown rounding modes are still printable
+	if unknown := RoundingMode(42); unknown.String() != "RoundingMode(42)" {
+		t.Errorf("unknown rounding mode should be printed as \"RoundingMode(42)\", got: \"%s\"", unknown.String())
+	}
+	if s := fmt.Sprintf("%v", RoundHalfEven); s != "half-even" {
+		t.Errorf("rounding mode should be printed using %%v as \"half-even\", got: \"%s\"", s)
+	}
+
+	// The rounding mode set for the currency on the invoice is used by default
+	for _, test := range []struct{
+		options []InvoiceOption
+		total   Money
+	}{
+		{nil, Money{3, SwissFranc}},
+		{[]InvoiceOption{WithCurrencyRounding(SwissFranc, RoundDown)}, Money{2, SwissFranc}},
+		{[]InvoiceOption{WithCurrencyRounding(SwissFranc, RoundDown), WithRounding(RoundHalfUp)}, Money{3, SwissFranc}},
+		{[]InvoiceOption{WithCurrencyRounding(GreatBritishPound, RoundDown)}, Money{3, SwissFranc}},
+	} {
+		invoice, err := testInvoice("d:Did thing;h:0.5;r:CHF 0.05", test.options...)
+		if err != nil {
+			t.Errorf("creating invoice is not supposed To return error: \"%s\"", err.Error())
+			continue
+		}
+		if total, err := invoice.Total(); err != nil || *total != test.total {
+			t.Errorf("CHF 0.05 × 0.5 should be %v, got: %v (%v)", &test.total, total, err)
+		}
+		if s := invoice.String(); !strings.Contains(s, "Subtotal: " + test.total.String()) {
+			t.Errorf("invoice should be printed with the subtotal %v, got: \"%s\"", &test.total, s)
+		}
+	}
+
+	// The rounding modes of each currency can be parsed
+	var roundings CurrencyRoundings
+	if err := roundings.Set("chf half-even, ¥ down"); err != nil {
+		t.Errorf("parsing currency roundings is not supposed To return error: \"%s\"", err.Error())
+	} else if roundings["CHF"] != RoundHalfEven || roundings["JPY"] != RoundDown || roundings.String() != "CHF half-even, JPY down" {
+		t.Errorf("currency roundings \"chf half-even, ¥ down\" were parsed as: %v", roundings)
+	}
+	for _, input := range []string{"CHF", "XYZ down", "CHF sideways", "CHF down, CHF up"} {
+		if err := roundings.Set(input); err == nil {
+			t.Errorf("parsing currency roundings \"%s\" should return an error", input)
+		}
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	for _, test := range []struct{
+		money  Money
+		ratios []uint
+		out    []int64
+		err    error
+	}{
+		{Money{10000, GreatBritishPound}, []uint{1, 1, 1}, []int64{3334, 3333, 3333}, nil},
+		{Money{5, GreatBritishPound}, []uint{3, 7}, []int64{2, 3}, nil},
+		{Money{100, JapaneseYen}, []uint{1, 0, 2}, []int64{33, 0, 67}, nil},
+		{Money{-10000, GreatBritishPound}, []uint{1, 1, 1}, []int64{-3334, -3333, -3333}, nil},
+		{Money{100, JapaneseYen}, []uint{0, 0}, nil, errors.New("cannot allocate Money using ratios that sum To zero")},
+	} {
+		parts, err := test.money.Allocate(test.ratios...)
+		if err != nil || test.err != nil {
+			if err == nil || test.err == nil || err.Error() != test.err.Error() {
+				t.Errorf("allocating %v using %v should return error \"%v\", got: \"%v\"", &test.money, test.ratios, test.err, err)
+			}
+			continue
+		}
+		for i, part := range parts {
+			if part.Money != test.out[i] || part.Currency != test.money.Currency {
+				t.Errorf("part %d of allocating %v using %v should be %d, got: %v", i + 1, &test.money, test.ratios, test.out[i], part)
+			}
+		}
+	}
+
+	// No minor units should ever be lost
+	if err := quick.Check(func(a int32, ratios []uint8) bool {
+		m := &Money{int64(a), Euro}
+		rs := make([]uint, len(ratios) + 1)
+		rs[0] = 1
+		for i, ratio := range ratios {
+			rs[i + 1] = uint(ratio)
+		}
+		parts, err := m.Allocate(rs...)
+		if err != nil {
+			return false
+		}
+		sum := &Money{0, Euro}
+		for _, part := range parts {
+			sum = sum.Add(part)
+		}
+		return *sum == *m
+	}, nil); err != nil {
+		t.Errorf("Allocate loses minor units: %v", err)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	for _, test := range []struct{
+		locale string
+		money  Money
+		symbol string
+		abbr   string
+		format string
+	}{
+		{"default", Money{123456, GreatBritishPound}, "£1234.56", "GBP 1234.56", "GBP 1234.56"},
+		{"en-GB", Money{-123456, GreatBritishPound}, "-£1,234.56", "GBP -1,234.56", "-£1,234.56"},
+		{"en_us", Money{123456789, UnitedStatesDollar}, "$1,234,567.89", "USD 1,234,567.89", "$1,234,567.89"},
+		{"de-DE", Money{123456, Euro}, "1.234,56 €", "1.234,56 EUR", "1.234,56 €"},
+		{"de-DE", Money{-123456, Euro}, "-1.234,56 €", "-1.234,56 EUR", "-1.234,56 €"},
+		{"fr-FR", Money{123456, Euro}, "1\u00a0234,56 €", "1\u00a0234,56 EUR", "1\u00a0234,56 €"},
+		{"nl-NL", Money{-123456, Euro}, "€ -1.234,56", "EUR -1.234,56", "€ -1.234,56"},
+		{"de-CH", Money{123456, SwissFranc}, "CHF 1'234.56", "CHF 1'234.56", "CHF 1'234.56"},
+		{"de-DE", Money{1234567, JapaneseYen}, "1.234.567 ¥", "1.234.567 JPY", "1.234.567 ¥"},
+		{"de-DE", Money{1234567, KuwaitiDinar}, "1.234,567 KWD", "1.234,567 KWD", "1.234,567 KWD"},
+	} {
+		var locale Locale
+		if err := locale.Set(test.locale); err != nil {
+			t.Errorf("parsing locale \"%s\" is not supposed To return error: \"%s\"", test.locale, err.Error())
+			continue
+		}
+		if s := locale.Symbol(&test.money); s != test.symbol {
+			t.Errorf("expected Symbol() output for %s (%s) does not match actual output: %s", test.locale, test.symbol, s)
+		}
+		if s := locale.Abbr(&test.money); s != test.abbr {
+			t.Errorf("expected Abbr() output for %s (%s) does not match actual output: %s", test.locale, test.abbr, s)
+		}
+		if s := locale.Format(&test.money); s != test.format {
+			t.Errorf("expected Format() output for %s (%s) does not match actual output: %s", test.locale, test.format, s)
+		}
+
+		// Each formatted value should be parsed back into the same Money
+		for _, s := range []string{test.symbol, test.abbr} {
+			money, err := ParseMoneyLocale(s, &locale)
+			if err != nil {
+				t.Errorf("parsing money \"%s\" using %s is not supposed To return error: \"%s\"", s, test.locale, err.Error())
+			} else if *money != test.money {
+				t.Errorf("parsing money \"%s\" using %s should give %v, got: %v", s, test.locale, &test.money, money)
+			}
+		}
+	}
+
+	var locale Locale
+	if err := locale.Set("xx-XX"); err == nil || !strings.Contains(err.Error(), "\"xx-XX\" is not a valid locale") {
+		t.Errorf("parsing locale \"xx-XX\" should return an error, got: %v", err)
+	}
+
+	// The Locale of an Invoice is used for its contents without changing how Money is formatted elsewhere
+	invoice, err := testInvoice("d:Thing;r:EUR 1234.56", WithLocale(LocaleFromName("de-DE")))
+	if err != nil {
+		t.Fatalf("creating invoice is not supposed To return error: \"%s\"", err.Error())
+	}
+	contents, _ := invoice.getContents(false, false)
+	if row := contents[0]; row[len(row) - 1] != "1.234,56 €" {
+		t.Errorf("the subtotal of an invoice using de-DE should be \"1.234,56 €\", got: \"%s\"", row[len(row) - 1])
+	}
+	if s := (&Money{123456, Euro}).String(); s != "EUR €1234.56" {
+		t.Errorf("Money should be formatted using the default locale as \"EUR €1234.56\", got: \"%s\"", s)
+	}
+}
+
+func TestTaxRate(t *testing.T) {
+	for _, test := range []struct{
+		input string
+		net   Money
+		tax   Money
+		str   string
+	}{
+		{"20%", Money{1000, GreatBritishPound}, Money{200, GreatBritishPound}, "20%"},
+		{"standard", Money{-1000, GreatBritishPound}, Money{-200, GreatBritishPound}, "20%"},
+		{"Reduced", Money{999, GreatBritishPound}, Money{50, GreatBritishPound}, "5%"},
+		{"zero", Money{999, GreatBritishPound}, Money{0, GreatBritishPound}, "0%"},
+		{"7.7 %", Money{1000, SwissFranc}, Money{77, SwissFranc}, "7.7%"},
+		{"8.875%", Money{1000, UnitedStatesDollar}, Money{89, UnitedStatesDollar}, "8.875%"},
+		{"10%", Money{15, JapaneseYen}, Money{2, JapaneseYen}, "10%"},
+	} {
+		var tr TaxRate
+		if err := tr.Set(test.input); err != nil {
+			t.Errorf("parsing tax rate \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			continue
+		}
+		if tax := tr.Tax(&test.net, RoundDefault); *tax != test.tax {
+			t.Errorf("tax of %s on %v should be %v, got: %v", test.input, &test.net, &test.tax, tax)
+		}
+		if s := tr.String(); s != test.str {
+			t.Errorf("expected String() output (%s) does not match actual output: %s", test.str, s)
+		}
+	}
+}
+
+func TestTaxes(t *testing.T) {
+	for _, test := range []struct{
+		input string
+		err   error
+		str   string
+		rate  string
+	}{
+		{input: "20%", str: "20%", rate: "20%"},
+		{input: "VAT standard", str: "VAT 20%", rate: "20%"},
+		{input: "GST 5% (Canada)|QST 9.975 % (Quebec)", str: "GST 5% (Canada) | QST 9.975% (Quebec)", rate: "14.975%"},
+		{input: "GST 5% | QST 7.5% Compound", str: "GST 5% | QST 7.5% compound", rate: "12.875%"},
+		{input: "Sales Tax 8.875% (New York City)", str: "Sales Tax 8.875% (New York City)", rate: "8.875%"},
+		{input: "GST (Canada)", err: errors.New("\"GST (Canada)\" is not a valid tax rate")},
+		{input: "GST 5% | compound", err: errors.New("\"compound\" is not a valid tax rate")},
+	} {
+		var taxes Taxes
+		err := taxes.Set(test.input)
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("parsing taxes \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("parsing taxes \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("parsing taxes \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else {
+			if s := taxes.String(); s != test.str {
+				t.Errorf("expected String() output (%s) does not match actual output: %s", test.str, s)
+			}
+			if rate := taxes.Rate(); rate.String() != test.rate {
+				t.Errorf("taxes \"%s\" should have a combined rate of %s, got: %s", test.input, test.rate, rate.String())
+			}
+		}
+	}
+}
@@ -4,173 +4,248 @@ package api
 import (
 	"errors"
 	"fmt"
-	"github.com/andygello555/ginvoice/globals"
 	"github.com/andygello555/gotils/ints"
-	"github.com/andygello555/gotils/misc"
-	str "github.com/andygello555/gotils/strings"
-	"reflect"
+	"math"
+	"math/big"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
 
-type KeyValueFlags interface {
-	KeyVal(keyVal string) (interface{}, error)
-}
-
-func keyValLogic(keyVal string, t KeyValueFlags, secondLevelSplit *regexp.Regexp, possibleKeyMapping *map[interface{}]map[string]struct{}) (interface{}, error) {
-	found := false
-	keyValSplit := globals.KeyValueSplit.Split(keyVal, 2)
-	keyValName := strings.Trim(str.TypeName(t), "*api.")
-
-	// If we don't find a key and a value then we fill out an appropriate error
-	if len(keyValSplit) != 2 {
-		// Create a nice help text lookup
-		var builder strings.Builder
-		i := 0
-		for _, mapping := range *possibleKeyMapping {
-			builder.WriteString("\t- The following are all possible prefixes for \"" + reflect.TypeOf(t).Elem().Field(i).Name + "\"\n")
-			for possible := range mapping {
-				builder.WriteString("\t\t- " + possible + "\n")
-			}
-			i ++
-		}
-		builder.WriteString("\n")
-		return nil, errors.New(fmt.Sprintf("%s details: cannot find key in text: \"%s\", keys must be one or more character long followed by a ':' then an optional whitespace\nany one of the following keys are valid (case insensitive):\n%s", keyValName, keyVal, builder.String()))
-	}
+type Item struct {
+	Description   string      `kv:"description,desc,d" required:"true"`
+	// HoursQuantity is the number of Unit(s) of the Item that are charged at the Rate.
+	HoursQuantity Quantity    `kv:"hoursquantity,hours,hrs,h,quantity,qty,q" default:"1"`
+	// Unit is the unit of measure of the HoursQuantity. This is optional.
+	Unit          Unit        `kv:"unit,uom,u"`
+	Rate          Money       `kv:"rate,r" required:"true"`
+	// Discount is taken off Rate × HoursQuantity before tax is charged. A fixed Discount is taken off the whole line
+	// rather than each unit.
+	Discount      Discount    `kv:"discount,disc"`
+	// Tax is the list of TaxComponent(s) charged on the Item. If there are none then the Item is taxed at 0%.
+	Tax           Taxes       `kv:"tax,t"`
+	// TaxInclusive is whether the Rate already includes Tax, in which case the net amount and tax are calculated
+	// backwards From Rate × HoursQuantity.
+	TaxInclusive  bool        `kv:"taxinclusive,inclusive,incl,gross"`
+	// Category is the TaxCategory of the Item. If this is empty then the TaxCategory is TaxStandard if the Tax is not
+	// 0%, otherwise it is TaxZeroRated.
+	Category      TaxCategory `kv:"category,cat"`
+	// Exemption is the reason (or VATEX code) why the Item is exempt From tax. This is required for TaxExempt items.
+	Exemption     string      `kv:"exemption,reason,vatex"`
+	// SKU is the seller's code for the Item. This is optional.
+	SKU           string      `kv:"sku,code,itemcode"`
+	// BuyerRef is the buyer's reference for the Item, such as the line of their purchase order. This is optional.
+	BuyerRef      string      `kv:"buyerref,buyerreference,po,poline,ref"`
+	// Period is the period that the Item was supplied over. This is optional.
+	Period        Period      `kv:"period,serviceperiod,service"`
+	// Note is any extra information about the Item. This is optional.
+	Note          string      `kv:"note,notes,comment"`
+	// Group is the name of the section that the Item is shown under, such as the phase of a project. This is
+	// optional.
+	Group         string      `kv:"group,section,phase"`
+}
 
-	key := strings.ToLower(keyValSplit[0])
-	val := keyValSplit[1]
-
-	// We iterate over all the possible mappings and set the relative fields accordingly
-	var prop interface{}
-	var possible map[string]struct{}
-	for prop, possible = range *possibleKeyMapping {
-		if _, ok := possible[key]; ok {
-			found = true
-			switch prop.(type) {
-			case *string:
-				*prop.(*string) = val
-			case *[]string:
-				// If we are on a string slice then we will split at each second level char
-				*prop.(*[]string) = secondLevelSplit.Split(val, -1)
-			case *uint:
-				i, err := strconv.Atoi(val)
-				if err != nil {
-					return nil, err
-				}
-				*prop.(*uint) = uint(i)
-			case *Money:
-				m, err := ParseMoney(val)
-				if err != nil {
-					return nil, err
-				}
-				*prop.(*Money) = *m
-			default:
-				return nil, errors.New(fmt.Sprintf("cannot set field of type \"%s\" To \"%v\"", str.TypeName(prop), val))
-			}
-			break
-		}
+// TaxCategory returns the TaxCategory of the Item.
+func (i *Item) TaxCategory() TaxCategory {
+	switch {
+	case i.Category != "":
+		return i.Category
+	case i.Tax.IsZero():
+		return TaxZeroRated
+	default:
+		return TaxStandard
 	}
+}
 
-	// If we didn't find any key-value pairs then we return an appropriate error
-	var err error = nil
-	if !found {
-		err = errors.New(fmt.Sprintf("%s details: could not find any key-value pair within \"%s\"", keyValName, keyVal))
-	}
-	return prop, err
+// Amount returns Rate × HoursQuantity before the Discount is taken off, rounded using the given RoundingMode.
+func (i *Item) Amount(mode RoundingMode) *Money {
+	return i.Rate.MulRounded(i.HoursQuantity.rat(), mode)
 }
 
-func setLogic(value string, t KeyValueFlags, firstLevelSplit *regexp.Regexp) error {
-	typeName := strings.TrimLeft(str.TypeName(t), "*api.")
-	var err error = nil
-	var fieldP interface{}
-	foundKeysSet := make(map[interface{}]struct{})
-	for _, keyVal := range firstLevelSplit.Split(value, -1) {
-		if fieldP, err = t.KeyVal(keyVal); err == nil {
-			if _, ok := foundKeysSet[fieldP]; !ok {
-				foundKeysSet[fieldP] = struct{}{}
-			} else {
-				err = errors.New(fmt.Sprintf("specified the same %s field multiple times in list of key-value pairs", typeName))
-				break
-			}
-			continue
-		}
-		break
-	}
+// DiscountAmount returns the amount taken off the Item by its Discount, rounded using the given RoundingMode. This is
+// negative unless the Discount is a surcharge.
+func (i *Item) DiscountAmount(mode RoundingMode) *Money {
+	return i.Discount.Of(i.Amount(mode), mode)
+}
 
-	// If we have a mismatch of the Number of fields then fill out an error
-	bankType := reflect.TypeOf(t)
-	numFields := bankType.Elem().NumField()
-	if err == nil && numFields != len(foundKeysSet) {
-		// Here we append a list of all fields within the Bank struct
-		var fieldNames strings.Builder
-		for i := 0; i < numFields; i++ {
-			fieldNames.WriteString("\t- " + bankType.Elem().Field(i).Name + "\n")
-		}
-		err = errors.New(fmt.Sprintf("%s details: you need To give %d key-value pairs each of which representing one of the following fields:\n%s", typeName, numFields, fieldNames.String()))
+// split returns the net amount and tax of the Item, rounding using the given RoundingMode. If inclusive is true then
+// Rate × HoursQuantity, minus the Discount, is treated as the gross amount.
+func (i *Item) split(mode RoundingMode, inclusive bool) (net *Money, tax *Money) {
+	amount := i.Amount(mode).Add(i.DiscountAmount(mode))
+	if inclusive {
+		rate := i.Tax.Rate()
+		net = rate.Net(amount, mode)
+		return net, amount.Sub(net)
 	}
-	return err
+	return amount, sumMoney(amount.Currency, i.Tax.Tax(amount, mode)...)
 }
 
-type Item struct {
-	Description   string
-	HoursQuantity uint
-	Rate          Money
-	Tax           Money
+// Net returns the net amount of the Item, rounded To the nearest minor unit using the given RoundingMode. This is
+// Rate × HoursQuantity minus the Discount, unless the Item is TaxInclusive in which case the Tax is also taken away.
+func (i *Item) Net(mode RoundingMode) *Money {
+	net, _ := i.split(mode, i.TaxInclusive)
+	return net
 }
 
+// TaxAmount returns the tax charged on the Net of the Item, rounded using the given RoundingMode.
+func (i *Item) TaxAmount(mode RoundingMode) *Money {
+	_, tax := i.split(mode, i.TaxInclusive)
+	return tax
+}
+
+// Subtotal returns the Net of the Item plus its TaxAmount. Both are rounded half up To the nearest minor unit, as an
+// Item does not know the RoundingMode of the Invoice it is on. Use SubtotalRounded with the RoundingMode of the
+// Invoice, or Invoice.ItemSubtotal, instead.
 func (i *Item) Subtotal() *Money {
-	return i.Rate.Multiply(float64(i.HoursQuantity)).Add(i.Tax.Float64())
+	return i.SubtotalRounded(RoundDefault)
+}
+
+// SubtotalRounded returns the Net of the Item plus its TaxAmount, rounding both using the given RoundingMode.
+func (i *Item) SubtotalRounded(mode RoundingMode) *Money {
+	net, tax := i.split(mode, i.TaxInclusive)
+	return net.Add(tax)
+}
+
+// Currency returns the Currency of the Item.
+func (i *Item) Currency() Currency {
+	return i.Rate.Currency
+}
+
+// QuantityString returns the HoursQuantity of the Item followed by the name of its Unit, if it has one (e.g. "7.5
+// hours").
+func (i *Item) QuantityString() string {
+	if i.Unit == "" {
+		return i.HoursQuantity.String()
+	}
+	return i.HoursQuantity.String() + " " + i.Unit.Name(&i.HoursQuantity)
+}
+
+// Details returns the optional SKU, BuyerRef, Period and Note of the Item, each of which is labelled (e.g. "SKU:
+// ABC-123"). The details that have not been given are left out.
+func (i *Item) Details() []string {
+	details := make([]string, 0)
+	if i.SKU != "" {
+		details = append(details, "SKU: " + i.SKU)
+	}
+	if i.BuyerRef != "" {
+		details = append(details, "Buyer ref: " + i.BuyerRef)
+	}
+	if !i.Period.IsZero() {
+		details = append(details, "Period: " + i.Period.String())
+	}
+	if i.Note != "" {
+		details = append(details, "Note: " + i.Note)
+	}
+	return details
 }
 
 func (i *Item) String() string {
-	return fmt.Sprintf("HRS/QTY: %d, RATE: %s, TAX: %s, Subtotal: %s", i.HoursQuantity, i.Rate.String(), i.Tax.String(), i.Subtotal().String())
-}
-
-func (i *Item) KeyVal(keyVal string) (interface{}, error) {
-	possibleKeyMappings := map[interface{}]map[string]struct{}{
-		&i.Description: {
-			"description": {},
-			"desc": {},
-			"d": {},
-		},
-		&i.HoursQuantity: {
-			"hoursquantity": {},
-			"hours":         {},
-			"hrs":           {},
-			"h":             {},
-			"quantity":      {},
-			"qty":           {},
-			"q":             {},
-		},
-		&i.Rate: {
-			"rate": {},
-			"r":    {},
-		},
-		&i.Tax: {
-			"tax": {},
-			"t":   {},
-		},
+	return i.describe(i.Subtotal())
+}
+
+// describe returns the Item as a string with the given subtotal.
+func (i *Item) describe(subtotal *Money) string {
+	tax := i.Tax.String()
+	if i.TaxInclusive {
+		tax += " incl."
+	}
+	var s string
+	if !i.Discount.IsZero() {
+		s = fmt.Sprintf("HRS/QTY: %s, RATE: %s, DISCOUNT: %s, TAX: %s, Subtotal: %s", i.QuantityString(), i.Rate.String(), i.Discount.String(), tax, subtotal.String())
+	} else {
+		s = fmt.Sprintf("HRS/QTY: %s, RATE: %s, TAX: %s, Subtotal: %s", i.QuantityString(), i.Rate.String(), tax, subtotal.String())
+	}
+	if details := i.Details(); len(details) > 0 {
+		s += ", " + strings.Join(details, ", ")
+	}
+	return s
+}
+
+func (i *Item) KeyVal(keyVal string, config *ParserConfig) (interface{}, error) {
+	return keyValLogic(keyVal, i, config, config.Separators.ThirdLevelSplit())
+}
+
+// check defaults and validates the fields of the Item that depend on each other once they have been set.
+func (i *Item) check() error {
+	// Durations are always converted into hours, so they cannot be given in any other Unit
+	if i.HoursQuantity.Rounding != nil {
+		if i.Unit != "" && i.Unit != "HUR" {
+			return errors.New(fmt.Sprintf("item \"%s\" has a quantity given as a duration, which is in hours, but a unit of %s", i.Description, i.Unit.Name(&i.HoursQuantity)))
+		}
+		i.Unit = "HUR"
+	}
+	// The Rate multiplied by the quantity must fit in Money, otherwise calculating the Subtotal would panic
+	if _, err := roundMoney(new(big.Rat).Mul(new(big.Rat).SetInt64(i.Rate.Money), i.HoursQuantity.rat()), RoundHalfUp, i.Rate.Currency); err != nil {
+		return errors.New(fmt.Sprintf("item \"%s\" has a subtotal that is too large: %s", i.Description, err.Error()))
+	}
+	// A fixed Discount must be in the same currency as the Rate, and cannot be larger than the amount it is taken off
+	if i.Discount.Amount != nil && i.Discount.Amount.Currency != i.Rate.Currency {
+		return errors.New(fmt.Sprintf("item \"%s\" has a discount in %s but a rate in %s", i.Description, i.Discount.Amount.Currency.Abbr, i.Rate.Currency.Abbr))
 	}
-	return keyValLogic(keyVal, i, globals.ThirdLevelSplit, &possibleKeyMappings)
+	if amount := i.Amount(RoundHalfUp); i.Discount.Amount != nil && !i.Discount.Surcharge && amount.Money > 0 && i.Discount.Amount.Money > amount.Money {
+		return errors.New(fmt.Sprintf("item \"%s\" has a discount of %s, which is larger than its amount of %s", i.Description, i.Discount.String(), amount.StringAbbr()))
+	}
+	return nil
 }
 
 type Items []*Item
 
-func (is *Items) Total() *Money {
-	var currency Currency
-	if len(*is) > 0 {
-		currency = (*is)[0].Rate.Currency
-	} else {
-		currency = ZeroCurrency
+// Total returns the sum of the Subtotal of each Item, which are rounded half up. Use TotalRounded with the RoundingMode
+// of the Invoice, or Invoice.Total, instead. An error is returned if the Items are in different currencies.
+func (is *Items) Total() (*Money, error) {
+	return is.TotalRounded(RoundDefault)
+}
+
+// TotalRounded returns the sum of the SubtotalRounded of each Item using the given RoundingMode. An error is returned
+// if the Items are in different currencies.
+func (is *Items) TotalRounded(mode RoundingMode) (*Money, error) {
+	currency, err := is.Currency()
+	if err != nil {
+		return nil, err
 	}
-	total := ToMoney(0, currency)
+	// As each Subtotal is already in minor units, the total is an exact sum of the subtotals
+	total := &Money{0, currency}
+	for _, item := range *is {
+		total = total.Add(item.SubtotalRounded(mode))
+	}
+	return total, nil
+}
+
+// ItemGroup is a named group of Items, such as the phase of a project.
+type ItemGroup struct {
+	Name  string
+	Items Items
+}
+
+// Groups returns the Items grouped by their Group, in the order that each Group first appears. The order of the Items
+// within each ItemGroup is kept. Items without a Group are in an ItemGroup without a Name.
+func (is *Items) Groups() []*ItemGroup {
+	groups := make([]*ItemGroup, 0)
+	byName := make(map[string]*ItemGroup)
 	for _, item := range *is {
-		total = total.Add(item.Subtotal().Float64())
+		group, ok := byName[item.Group]
+		if !ok {
+			group = &ItemGroup{Name: item.Group, Items: make(Items, 0)}
+			byName[item.Group] = group
+			groups = append(groups, group)
+		}
+		group.Items = append(group.Items, item)
+	}
+	return groups
+}
+
+// Currency returns the Currency shared by all Items. An error is returned if the Items are in different currencies.
+func (is *Items) Currency() (Currency, error) {
+	currency := ZeroCurrency
+	for n, item := range *is {
+		itemCurrency := item.Currency()
+		if n == 0 {
+			currency = itemCurrency
+		} else if itemCurrency != currency {
+			return ZeroCurrency, errors.New(fmt.Sprintf("items have mixed currencies: item 1 is in %s but item %d is in %s", currency.Abbr, n + 1, itemCurrency.Abbr))
+		}
 	}
-	return total
+	return currency, nil
 }
 
 func (is *Items) String() string {
@@ -181,48 +256,36 @@ func (is *Items) String() string {
 	return b.String()
 }
 
+// Format returns the Items in the same syntax that they are parsed From by Set. Values that contain a separator are
+// quoted.
+func (is *Items) Format() string {
+	return is.FormatWith(DefaultParserConfig)
+}
+
+// FormatWith returns the Items in the same syntax that they are parsed From by SetWith using the given ParserConfig.
+func (is *Items) FormatWith(config *ParserConfig) string {
+	items := make([]string, len(*is))
+	for n, item := range *is {
+		items[n] = formatKeyVals(item, config, config.Separators.SecondLevel, config.Separators.ThirdLevel)
+	}
+	return strings.Join(items, config.Separators.FirstLevel + " ")
+}
+
+// Set the Items From a list of items, each of which is a list of key-value pairs. The Offset of any error is relative
+// To the start of the value.
 func (is *Items) Set(value string) error {
+	return is.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the Items like Set, but using the given ParserConfig instead of the DefaultParserConfig.
+func (is *Items) SetWith(config *ParserConfig, value string) error {
 	var err error = nil
 	items := make([]*Item, 0)
-	for _, itemStr := range globals.FirstLevelSplit.Split(value, -1) {
+	itemStrs, offsets := config.Separators.FirstLevelSplit().SplitOffsets(value, -1)
+	for n, itemStr := range itemStrs {
 		item := Item{}
-		err = setLogic(itemStr, &item, globals.SecondLevelSplit)
-		// Here we count how many empty fields we have using reflection. This is so we can default the HoursQuantity
-		// value To 1 if no HoursQuantity value is given.
-		valueOf := reflect.ValueOf(item)
-		emptyMoney := Money{}
-		clear := true
-		for i := 0; i < reflect.TypeOf(item).NumField(); i++ {
-			val := reflect.Indirect(valueOf).Field(i)
-			switch reflect.TypeOf(item).Field(i).Name {
-			case "HoursQuantity":
-				if val.Interface().(uint) == 0 {
-					item.HoursQuantity = 1
-				}
-			case "Tax":
-				if val.Interface().(Money) == emptyMoney {
-					item.Tax = Money{
-						Money:    0,
-						Currency: ZeroCurrency,
-					}
-				}
-			case "Description":
-				if len(val.String()) == 0 {
-					clear = false
-				}
-			case "Rate":
-				if val.Interface().(Money) == emptyMoney {
-					clear = false
-				}
-			}
-		}
-
-		// Here clear is true then we can clear out the error
-		if clear {
-			err = nil
-		}
-		if err != nil {
-			return err
+		if err = setLogic(itemStr, &item, config, config.Separators.SecondLevelSplit()); err != nil {
+			return shiftError(err, offsets[n])
 		}
 		items = append(items, &item)
 	}
@@ -231,103 +294,67 @@ func (is *Items) Set(value string) error {
 }
 
 type Contact struct {
-	Company   string
-	FirstName string
-	LastName  string
-	Email     string
-	PhoneNo   string
-	Address   []string
+	Company   string   `kv:"company,comp,c"`
+	FirstName string   `kv:"firstname,first,f" required:"true"`
+	LastName  string   `kv:"lastname,last,l" required:"true"`
+	Email     string   `kv:"email,e" required:"true" validate:"email"`
+	PhoneNo   string   `kv:"phoneno,phonenumber,phone,p" required:"true"`
+	Address   []string `kv:"address,addr,a" required:"true"`
+	// VATID is the VAT identification number of the contact, including its country prefix (e.g. "DE123456789"). This is
+	// optional unless the contact is party To a reverse charge or intra-community supply.
+	VATID     string   `kv:"vatid,vat,vatno,v" validate:"vatid"`
 }
 
 func (c *Contact) String() string {
-	return fmt.Sprintf(`%s
+	s := fmt.Sprintf(`%s
 %s %s
 %s
 
 %s
 %s
 `, c.Company, c.FirstName, c.LastName, strings.Join(c.Address, "\n"), c.Email, c.PhoneNo)
+	if c.VATID != "" {
+		s += "VAT ID: " + c.VATID + "\n"
+	}
+	return s
 }
 
-func (c *Contact) KeyVal(keyVal string) (interface{}, error) {
-	possibleKeyMappings := map[interface{}]map[string]struct{} {
-		&c.Company: {
-			"company": {},
-			"c": {},
-			"comp": {},
-		},
-		&c.FirstName: {
-			"firstname": {},
-			"first": {},
-			"f": {},
-		},
-		&c.LastName: {
-			"lastname": {},
-			"last": {},
-			"l": {},
-		},
-		&c.Email: {
-			"email": {},
-			"e": {},
-		},
-		&c.PhoneNo: {
-			"phoneno": {},
-			"phonenumber": {},
-			"phone": {},
-			"p": {},
-		},
-		&c.Address: {
-			"address": {},
-			"addr": {},
-			"a": {},
-		},
-	}
-	return keyValLogic(keyVal, c, globals.SecondLevelSplit, &possibleKeyMappings)
+func (c *Contact) KeyVal(keyVal string, config *ParserConfig) (interface{}, error) {
+	return keyValLogic(keyVal, c, config, config.Separators.SecondLevelSplit())
+}
+
+// Format returns the Contact in the same syntax that it is parsed From by Set. Values that contain a separator are
+// quoted.
+func (c *Contact) Format() string {
+	return c.FormatWith(DefaultParserConfig)
+}
+
+// FormatWith returns the Contact in the same syntax that it is parsed From by SetWith using the given ParserConfig.
+func (c *Contact) FormatWith(config *ParserConfig) string {
+	return formatKeyVals(c, config, config.Separators.FirstLevel, config.Separators.SecondLevel)
 }
 
 func (c *Contact) Set(value string) error {
-	err := setLogic(value, c, globals.FirstLevelSplit)
-	// Here we count how many empty fields we have using reflection. This is so we can default the Company value
-	// To FirstName + LastName if no Company value is given.
-	if err != nil && !strings.Contains(err.Error(), "cannot find key in text") || err == nil  {
-		valueOf := reflect.ValueOf(*c)
-		clear := true
-		for i := 0; i < reflect.TypeOf(Contact{}).NumField(); i++ {
-			val := reflect.Indirect(valueOf).Field(i)
-			switch reflect.TypeOf(Contact{}).Field(i).Name {
-			case "Address":
-				if len(val.Interface().([]string)) == 0 {
-					clear = false
-				}
-			case "Email":
-				// We validate the email address given
-				if !misc.IsEmailValid(val.String()) {
-					return errors.New(fmt.Sprintf("\"%s\" is not a valid email", val.String()))
-				}
-				fallthrough
-			case "Company":
-				if len(val.String()) == 0 {
-					c.Company = fmt.Sprintf("%s %s", c.FirstName, c.LastName)
-				}
-			default:
-				if len(val.String()) == 0 {
-					clear = false
-				}
-			}
-		}
+	return c.SetWith(DefaultParserConfig, value)
+}
 
-		// Here we clear the error only if all the required fields are set
-		if clear {
-			err = nil
-		}
+// SetWith sets the Contact like Set, but using the given ParserConfig instead of the DefaultParserConfig.
+func (c *Contact) SetWith(config *ParserConfig, value string) error {
+	return setLogic(value, c, config, config.Separators.FirstLevelSplit())
+}
+
+// check defaults the Company of the Contact To FirstName + LastName if no Company value is given.
+func (c *Contact) check() error {
+	if c.Company == "" {
+		c.Company = fmt.Sprintf("%s %s", c.FirstName, c.LastName)
 	}
-	return err
+	return nil
 }
 
 type Bank struct {
-	Bank      string
-	AccountNo string
-	SortCode  string
+	Bank      string `kv:"bank,b" required:"true"`
+	AccountNo string `kv:"accountno,account,a/c no.,a/c,a,no,acc" required:"true" validate:"accountno"`
+	SortCode  string `kv:"sortcode,sort,code,s" required:"true" validate:"sortcode"`
 }
 
 func (b *Bank) String() string {
@@ -338,29 +365,18 @@ Sort code: %s
 `, b.Bank, b.AccountNo, b.SortCode)
 }
 
-func (b *Bank) KeyVal(keyVal string) (interface{}, error) {
-	possibleKeyMapping := map[interface{}]map[string]struct{} {
-		&b.Bank: {
-			"bank": {},
-			"b": {},
-		},
-		&b.AccountNo: {
-			"accountno": {},
-			"account": {},
-			"a/c no.": {},
-			"a/c": {},
-			"a": {},
-			"no": {},
-			"acc": {},
-		},
-		&b.SortCode: {
-			"sortcode": {},
-			"sort": {},
-			"code": {},
-			"s": {},
-		},
-	}
-	return keyValLogic(keyVal, b, globals.SecondLevelSplit, &possibleKeyMapping)
+func (b *Bank) KeyVal(keyVal string, config *ParserConfig) (interface{}, error) {
+	return keyValLogic(keyVal, b, config, config.Separators.SecondLevelSplit())
+}
+
+// Format returns the Bank in the same syntax that it is parsed From by Set. Values that contain a separator are quoted.
+func (b *Bank) Format() string {
+	return b.FormatWith(DefaultParserConfig)
+}
+
+// FormatWith returns the Bank in the same syntax that it is parsed From by SetWith using the given ParserConfig.
+func (b *Bank) FormatWith(config *ParserConfig) string {
+	return formatKeyVals(b, config, config.Separators.FirstLevel, config.Separators.SecondLevel)
 }
 
 // Set the Bank value From the given string value.
@@ -370,45 +386,12 @@ func (b *Bank) KeyVal(keyVal string) (interface{}, error) {
 // A valid string value can be:
 //  Bank: Bank o' Clock, account: 12312312,sort: 69/69/69
 func (b *Bank) Set(value string) error {
-	err := setLogic(value, b, globals.FirstLevelSplit)
-	// Here we validate all the fields that have been set To make sure they are good values.
-	valueOf := reflect.ValueOf(*b)
-	if err != nil && !strings.Contains(err.Error(), "cannot find key in text") || err == nil {
-		for i := 0; i < reflect.TypeOf(Bank{}).NumField(); i++ {
-			val := reflect.Indirect(valueOf).Field(i)
-			switch reflect.TypeOf(Bank{}).Field(i).Name {
-			case "SortCode":
-				valStr := val.String()
-				errPrefix := fmt.Sprintf("\"%s\" is not a valid sort code", valStr)
-				var errStr string
-				if len(valStr) != 6 {
-					errStr += " (6 digits)"
-				}
-				if !str.IsNumeric(valStr) {
-					errStr += " (not numeric)"
-				}
-				if errStr != "" {
-					return errors.New(errPrefix + errStr)
-				}
-			case "AccountNo":
-				valStr := val.String()
-				errPrefix := fmt.Sprintf("\"%s\" is not a valid account number", valStr)
-				var errStr string
-				if len(valStr) != 8 {
-					errStr = " (8 digits)"
-				}
-				if !str.IsNumeric(valStr) {
-					errStr += " (not numeric)"
-				}
-				if errStr != "" {
-					return errors.New(errPrefix + errStr)
-				}
-			default:
-				break
-			}
-		}
-	}
-	return err
+	return b.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the Bank like Set, but using the given ParserConfig instead of the DefaultParserConfig.
+func (b *Bank) SetWith(config *ParserConfig, value string) error {
+	return setLogic(value, b, config, config.Separators.FirstLevelSplit())
 }
 
 type Date time.Time
@@ -422,6 +405,18 @@ func (d *Date) String() string {
 	)
 }
 
+// AddDays returns the Date that is the given number of days after the Date.
+func (d *Date) AddDays(days int) *Date {
+	date := Date(time.Time(*d).AddDate(0, 0, days))
+	return &date
+}
+
+// DaysUntil returns the number of days From the Date until the given Date. This is negative if the given Date is
+// before the Date.
+func (d *Date) DaysUntil(o *Date) int {
+	return int(math.Round(time.Time(*o).Sub(time.Time(*d)).Hours() / 24))
+}
+
 // Set the Date value From the given string value.
 //
 // If the given string cannot be parsed then an error will be returned otherwise the error will be nil.
@@ -430,10 +425,75 @@ func (d *Date) String() string {
 //  1/12/2000
 // Note that the format is:
 //  Day/Month/Year
+// Dates can also be given in ISO 8601 format:
+//  2000-12-01
 func (d *Date) Set(value string) error {
 	var err error = nil
 	var t time.Time
 	t, err = time.Parse("2/1/2006", value)
+	if err != nil {
+		// Dates From documents can also be given in ISO 8601 format
+		if iso, isoErr := time.Parse("2006-01-02", value); isoErr == nil {
+			t, err = iso, nil
+		}
+	}
 	*d = Date(t)
 	return err
 }
+
+// periodRegex matches a Period as its start and end Date(s), which can be separated by a hyphen, an en dash or "to".
+// Each Date can either be in D/M/YYYY or ISO 8601 format, so hyphens within ISO 8601 dates do not separate them.
+var periodRegex = regexp.MustCompile(`^([0-9]{1,2}/[0-9]{1,2}/[0-9]{4}|[0-9]{4}-[0-9]{2}-[0-9]{2}) *(?:-|–|\bto\b) *([0-9]{1,2}/[0-9]{1,2}/[0-9]{4}|[0-9]{4}-[0-9]{2}-[0-9]{2})$`)
+
+// Period is the period between two Date(s), such as the period that a service was supplied over. Both Date(s) are
+// inclusive.
+type Period struct {
+	Start Date
+	End   Date
+}
+
+// IsZero returns whether the Period has not been set.
+func (p *Period) IsZero() bool {
+	return time.Time(p.Start).IsZero() && time.Time(p.End).IsZero()
+}
+
+func (p *Period) String() string {
+	if p.IsZero() {
+		return ""
+	}
+	return p.Start.String() + " - " + p.End.String()
+}
+
+// format returns the Period in the same syntax that it is parsed From by Set (e.g. "1/12/2021-31/12/2021").
+func (p *Period) format() string {
+	return time.Time(p.Start).Format("2/1/2006") + "-" + time.Time(p.End).Format("2/1/2006")
+}
+
+// Set the Period From a start and end Date, which can be separated by a hyphen, an en dash or "to". The start Date
+// cannot be after the end Date.
+//
+// A valid string value can be:
+//  1/12/2021-31/12/2021
+// Or:
+//  1/12/2021 to 31/12/2021
+// Or:
+//  2021-12-01 to 2021-12-31
+func (p *Period) Set(value string) error {
+	match := periodRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid period, periods are given as a start and end date in D/M/YYYY or YYYY-MM-DD format (e.g. 1/12/2021-31/12/2021)", value))
+	}
+	dates := match[1:]
+	period := Period{}
+	if err := period.Start.Set(dates[0]); err != nil {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid period, \"%s\" is not a date in D/M/YYYY or YYYY-MM-DD format", value, dates[0]))
+	}
+	if err := period.End.Set(dates[1]); err != nil {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid period, \"%s\" is not a date in D/M/YYYY or YYYY-MM-DD format", value, dates[1]))
+	}
+	if period.Start.DaysUntil(&period.End) < 0 {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid period, the start date is after the end date", value))
+	}
+	*p = period
+	return nil
+}
@@ -2,9 +2,14 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"github.com/andygello555/ginvoice/globals"
+	"math/big"
+	"math/rand"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 )
 
@@ -52,12 +57,12 @@ func TestContact(t *testing.T) {
 		},
 		{
 			input: "lastName: Smith, email: johnsmith@example.com, phoneNo: 123123123, address: 1 Smith Street;Smith Town;Smith;SM20 123;UK",
-			err:   errors.New("Contact details: you need To give 6 key-value pairs each of which representing one of the following fields:\n\t- Company\n\t- FirstName\n\t- LastName\n\t- Email\n\t- PhoneNo\n\t- Address\n"),
+			err:   errors.New("Contact details: you need To give a key-value pair for each of the following required fields in \"lastName: Smith, email: johnsmith@example.com, phoneNo: 123123123, address: 1 Smith Street;Smith Town;Smith;SM20 123;UK\" at column 1:\n\t- FirstName (firstname, first, f)\n"),
 			out:   Contact{},
 		},
 		{
 			input: "firstName; John, lastName: Smith, email: johnsmith@example.com, phoneNo: 123123123, address: 1 Smith Street;Smith Town;Smith;SM20 123;UK",
-			err:   errors.New("Contact details: cannot find key in text: \"firstName; John\", keys must be one or more character long followed by a ':' then an optional whitespace"),
+			err:   errors.New("Contact details: cannot find key in text: \"firstName; John\" at column 1, keys must be one or more character long followed by a ':' then an optional whitespace"),
 			out:   Contact{},
 		},
 		{
@@ -78,6 +83,60 @@ func TestContact(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "f:John,l:Smith,e:johnsmith@example.com,p:123123123,a:1 Smith Street;UK,vat:gb 123 456 789",
+			err:   nil,
+			out:   Contact{
+				Company:   "John Smith",
+				FirstName: "John",
+				LastName:  "Smith",
+				Email:     "johnsmith@example.com",
+				PhoneNo:   "123123123",
+				Address:   []string{
+					"1 Smith Street",
+					"UK",
+				},
+				VATID:     "GB123456789",
+			},
+		},
+		{
+			input: "f:John,l:Smith,e:johnsmith@example.com,p:123123123,a:1 Smith Street;UK,vat:123456789",
+			err:   errors.New("\"123456789\" is not a valid VAT ID"),
+			out:   Contact{},
+		},
+		{
+			input: `company: "Smith, Jones & Co", f: John, l: Smith, e: johnsmith@example.com, p: 123123123, a: "1 Smith Street; Flat 2";Smith\; Town;UK`,
+			err:   nil,
+			out:   Contact{
+				Company:   "Smith, Jones & Co",
+				FirstName: "John",
+				LastName:  "Smith",
+				Email:     "johnsmith@example.com",
+				PhoneNo:   "123123123",
+				Address:   []string{
+					"1 Smith Street; Flat 2",
+					"Smith; Town",
+					"UK",
+				},
+			},
+		},
+		{
+			input: `company: John \"The Rock\" Smith\, Ltd, f: John, l: Smith, e: johnsmith@example.com, p: 123123123, a: UK`,
+			err:   nil,
+			out:   Contact{
+				Company:   "John \"The Rock\" Smith, Ltd",
+				FirstName: "John",
+				LastName:  "Smith",
+				Email:     "johnsmith@example.com",
+				PhoneNo:   "123123123",
+				Address:   []string{"UK"},
+			},
+		},
+		{
+			input: `company: "Smith, f: John, l: Smith, e: johnsmith@example.com, p: 123123123, a: UK`,
+			err:   errors.New("has an unclosed quote"),
+			out:   Contact{},
+		},
 		{
 			input: "f:John,l:Smith,e:not an email,p:123123123,a:1 Smith Street;Smith Town;Smith;SM20 123;UK",
 			err:   errors.New("\"not an email\" is not a valid email"),
@@ -174,6 +233,13 @@ func TestBank(t *testing.T) {
 }
 
 func TestItems(t *testing.T) {
+	taxes := func(value string) Taxes {
+		var ts Taxes
+		if err := ts.Set(value); err != nil {
+			t.Fatalf("cannot parse taxes \"%s\": %s", value, err.Error())
+		}
+		return ts
+	}
 	for _, test := range []struct{
 		input     string
 		err       error
@@ -182,105 +248,221 @@ func TestItems(t *testing.T) {
 		total     Money
 	}{
 		{
-			input: "d: Did thing 1; h: 10; r: $10; t: $0.1,d: Did thing 2; h: 10; r: $10; t: $0.1",
+			input: "d: Did thing 1; h: 10; r: $10; t: 20%,d: Did thing 2; h: 10; r: $10; t: standard",
 			out:   Items{
 				{
-					"Did thing 1",
-					10,
-					Money{
+					Description:   "Did thing 1",
+					HoursQuantity: NewQuantity(10),
+					Rate:          Money{
 						Money:    1000,
 						Currency: UnitedStatesDollar,
 					},
-					Money{
-						Money:    10,
+					Tax:           taxes("20%"),
+				},
+				{
+					Description:   "Did thing 2",
+					HoursQuantity: NewQuantity(10),
+					Rate:          Money{
+						Money:    1000,
 						Currency: UnitedStatesDollar,
 					},
+					Tax:           taxes("Standard"),
+				},
+			},
+			subtotals: []Money{
+				{
+					Money:    12000,
+					Currency: UnitedStatesDollar,
 				},
 				{
-					"Did thing 2",
-					10,
-					Money{
+					Money:    12000,
+					Currency: UnitedStatesDollar,
+				},
+			},
+			total: Money{
+				24000,
+				UnitedStatesDollar,
+			},
+		},
+		{
+			input: "d: Did thing 1; h: 10; r: $10,d: Did thing 2; h: 3; r: $3.33; t: 7.7%",
+			out:   Items{
+				{
+					Description:   "Did thing 1",
+					HoursQuantity: NewQuantity(10),
+					Rate:          Money{
 						Money:    1000,
 						Currency: UnitedStatesDollar,
 					},
-					Money{
-						Money:    10,
+				},
+				{
+					Description:   "Did thing 2",
+					HoursQuantity: NewQuantity(3),
+					Rate:          Money{
+						Money:    333,
 						Currency: UnitedStatesDollar,
 					},
+					Tax:           taxes("7.7%"),
 				},
 			},
 			subtotals: []Money{
 				{
-					Money:    10010,
+					Money:    10000,
 					Currency: UnitedStatesDollar,
 				},
 				{
-					Money:    10010,
+					// 9.99 + 0.76923 tax
+					Money:    1076,
 					Currency: UnitedStatesDollar,
 				},
 			},
 			total: Money{
-				20020,
+				11076,
 				UnitedStatesDollar,
 			},
 		},
 		{
-			input: "d: Did thing 1; h: 10; r: $10,d: Did thing 2; h: 10; r: $10; t: $0.1",
+			input: "d:Did thing 1;r:$10",
 			out:   Items{
 				{
-					"Did thing 1",
-					10,
-					Money{
+					Description:   "Did thing 1",
+					HoursQuantity: NewQuantity(1),
+					Rate:          Money{
 						Money:    1000,
 						Currency: UnitedStatesDollar,
 					},
-					Money{
-						Money:    0,
-						Currency: ZeroCurrency,
-					},
 				},
+			},
+			subtotals: []Money{
+				{
+					Money:    1000,
+					Currency: UnitedStatesDollar,
+				},
+			},
+			total: Money{
+				1000,
+				UnitedStatesDollar,
+			},
+		},
+		{
+			input: "d:Did thing 1;h:7.5;u:hours;r:$10, d:Did thing 2;q:0.125;unit:DAY;r:$400",
+			out:   Items{
 				{
-					"Did thing 2",
-					10,
-					Money{
+					Description:   "Did thing 1",
+					HoursQuantity: Quantity{Value: big.NewRat(15, 2)},
+					Unit:          "HUR",
+					Rate:          Money{
 						Money:    1000,
 						Currency: UnitedStatesDollar,
 					},
-					Money{
-						Money:    10,
+				},
+				{
+					Description:   "Did thing 2",
+					HoursQuantity: Quantity{Value: big.NewRat(1, 8)},
+					Unit:          "DAY",
+					Rate:          Money{
+						Money:    40000,
 						Currency: UnitedStatesDollar,
 					},
 				},
 			},
 			subtotals: []Money{
 				{
-					Money:    10000,
+					Money:    7500,
 					Currency: UnitedStatesDollar,
 				},
 				{
-					Money:    10010,
+					Money:    5000,
 					Currency: UnitedStatesDollar,
 				},
 			},
 			total: Money{
-				20010,
+				12500,
 				UnitedStatesDollar,
 			},
 		},
 		{
-			input: "d:Did thing 1;r:$10",
+			input: "d:Did thing 1;h:1h30m;r:$60, d:Did thing 2;h:01:45;u:days;r:$8",
+			err:   errors.New("item \"Did thing 2\" has a quantity given as a duration, which is in hours, but a unit of days"),
+		},
+		{
+			input: "d:Did thing 1;h:1h30m;r:$60, d:Did thing 2;h:01:45;u:hours;r:$8",
+			out:   Items{
+				{
+					Description:   "Did thing 1",
+					HoursQuantity: Quantity{Value: big.NewRat(3, 2), Rounding: &TimeRounding{time.Minute, RoundHalfUp}},
+					Unit:          "HUR",
+					Rate:          Money{
+						Money:    6000,
+						Currency: UnitedStatesDollar,
+					},
+				},
+				{
+					Description:   "Did thing 2",
+					HoursQuantity: Quantity{Value: big.NewRat(7, 4), Rounding: &TimeRounding{time.Minute, RoundHalfUp}},
+					Unit:          "HUR",
+					Rate:          Money{
+						Money:    800,
+						Currency: UnitedStatesDollar,
+					},
+				},
+			},
+			subtotals: []Money{
+				{
+					Money:    9000,
+					Currency: UnitedStatesDollar,
+				},
+				{
+					Money:    1400,
+					Currency: UnitedStatesDollar,
+				},
+			},
+			total: Money{
+				10400,
+				UnitedStatesDollar,
+			},
+		},
+		{
+			input: "d:Did thing 1;h:1h20m;r:$60",
+			out:   Items{
+				{
+					Description:   "Did thing 1",
+					HoursQuantity: Quantity{Value: big.NewRat(1333, 1000), Rounding: &TimeRounding{time.Minute, RoundHalfUp}},
+					Unit:          "HUR",
+					Rate:          Money{
+						Money:    6000,
+						Currency: UnitedStatesDollar,
+					},
+				},
+			},
+			subtotals: []Money{
+				{
+					Money:    7998,
+					Currency: UnitedStatesDollar,
+				},
+			},
+			total: Money{
+				7998,
+				UnitedStatesDollar,
+			},
+		},
+		{
+			input: "d:Did thing 1;sku:ABC-123;po:4500012/10;period:1/12/2021-31/12/2021;note:On site;r:$10",
 			out:   Items{
 				{
-					"Did thing 1",
-					1,
-					Money{
+					Description:   "Did thing 1",
+					HoursQuantity: NewQuantity(1),
+					Rate:          Money{
 						Money:    1000,
 						Currency: UnitedStatesDollar,
 					},
-					Money{
-						Money:    0,
-						Currency: ZeroCurrency,
+					SKU:           "ABC-123",
+					BuyerRef:      "4500012/10",
+					Period:        Period{
+						Start: Date(time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)),
+						End:   Date(time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC)),
 					},
+					Note:          "On site",
 				},
 			},
 			subtotals: []Money{
@@ -294,13 +476,115 @@ func TestItems(t *testing.T) {
 				UnitedStatesDollar,
 			},
 		},
+		{
+			input:     "d: Did thing; period: 31/12/2021 to 1/12/2021; r: $10",
+			err:       errors.New("\"31/12/2021 to 1/12/2021\" is not a valid period, the start date is after the end date"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     "d: Did thing; period: December; r: $10",
+			err:       errors.New("\"December\" is not a valid period"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     "d: Did thing; h: 7.5555; r: $10",
+			err:       errors.New("\"7.5555\" is not a valid quantity, quantities can have at most 3 decimal places"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     "d: Did thing; h: 1000; r: GBP 90000000000000000",
+			err:       errors.New("item \"Did thing\" has a subtotal that is too large: GBP 90000000000000000000.00 is too large To be represented as Money"),
+			out:       Items{},
+			subtotals: []Money{},
+		},
+		{
+			input:     "d: Did thing; h: 0; r: $10",
+			err:       errors.New("\"0\" is not a valid quantity, quantities must be greater than 0"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     "d: Did thing; h: 20s; r: $10",
+			err:       errors.New("\"20s\" is not a valid quantity, quantities must be greater than 0 but the duration is rounded To 0"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     "d: Did thing; h: -1; r: $10",
+			err:       errors.New("\"-1\" is not a valid quantity"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     `d: "Design, build; test"; r: $10; t: "VAT: 20%", d: Said \"hi\"\, twice; r: $5; note: "a|b"`,
+			out:       Items{
+				{
+					Description:   "Design, build; test",
+					HoursQuantity: NewQuantity(1),
+					Rate:          Money{
+						Money:    1000,
+						Currency: UnitedStatesDollar,
+					},
+					Tax:           taxes("VAT: 20%"),
+				},
+				{
+					Description:   "Said \"hi\", twice",
+					HoursQuantity: NewQuantity(1),
+					Rate:          Money{
+						Money:    500,
+						Currency: UnitedStatesDollar,
+					},
+					Note:          "a|b",
+				},
+			},
+			subtotals: []Money{
+				{
+					Money:    1200,
+					Currency: UnitedStatesDollar,
+				},
+				{
+					Money:    500,
+					Currency: UnitedStatesDollar,
+				},
+			},
+			total: Money{
+				1700,
+				UnitedStatesDollar,
+			},
+		},
+		{
+			input:     "d: Did thing; u: fortnights; r: $10",
+			err:       errors.New("\"fortnights\" is not a valid unit"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
 		{
 			input:     "r:$10",
-			err: 	   errors.New(`Item details: you need To give 4 key-value pairs each of which representing one of the following fields:
-	- Description
-	- HoursQuantity
-	- Rate
-	- Tax`),
+			err: 	   errors.New("Item details: you need To give a key-value pair for each of the following required fields in \"r:$10\" at column 1:\n\t- Description (description, desc, d)\n"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     "d: Did thing; r: $10; t: $0.10",
+			err:       errors.New("\"$0.10\" is not a valid tax rate, tax rates are given as a percentage (e.g. 20%) or one of the named rates: reduced, standard, zero"),
+			out:       Items{},
+			subtotals: []Money{},
+			total:     Money{},
+		},
+		{
+			input:     "d: Did thing; r: $10; t: -5%",
+			err:       errors.New("\"-5%\" is not a valid tax rate"),
 			out:       Items{},
 			subtotals: []Money{},
 			total:     Money{},
@@ -329,8 +613,10 @@ func TestItems(t *testing.T) {
 			}
 
 			// Check Items total
-			if *items.Total() != test.total {
-				t.Errorf("Items do not have the expected total of %v, instead it is: %v", &test.total, items.Total())
+			if total, err := items.Total(); err != nil {
+				t.Errorf("totalling Items \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+			} else if *total != test.total {
+				t.Errorf("Items do not have the expected total of %v, instead it is: %v", &test.total, total)
 			}
 		}
 	}
@@ -377,3 +663,225 @@ func TestDate(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeRounding(t *testing.T) {
+	for _, test := range []struct{
+		input    string
+		err      error
+		out      TimeRounding
+		str      string
+		describe string
+		// The durations that are rounded mapped To the expected result
+		rounded  map[time.Duration]time.Duration
+	}{
+		{
+			input:    "6m",
+			out:      TimeRounding{6 * time.Minute, RoundHalfUp},
+			str:      "6m",
+			describe: "Time is rounded to the nearest 6 minutes.",
+			rounded:  map[time.Duration]time.Duration{
+				62 * time.Minute: time.Hour,
+				63 * time.Minute: 66 * time.Minute,
+				0:                0,
+			},
+		},
+		{
+			input:    "15m up",
+			out:      TimeRounding{15 * time.Minute, RoundUp},
+			str:      "15m up",
+			describe: "Time is rounded up to the next 15 minutes.",
+			rounded:  map[time.Duration]time.Duration{
+				61 * time.Minute: 75 * time.Minute,
+				time.Hour:        time.Hour,
+			},
+		},
+		{
+			input:    "1h30m down",
+			out:      TimeRounding{90 * time.Minute, RoundDown},
+			str:      "1h30m down",
+			describe: "Time is rounded down to the previous 90 minutes.",
+			rounded:  map[time.Duration]time.Duration{
+				179 * time.Minute: 90 * time.Minute,
+			},
+		},
+		{
+			input:    "0",
+			out:      TimeRounding{0, RoundHalfUp},
+			str:      "0s",
+			describe: "Time is not rounded.",
+			rounded:  map[time.Duration]time.Duration{
+				61 * time.Second: 61 * time.Second,
+			},
+		},
+		{
+			input: "15",
+			err:   errors.New("\"15\" is not a valid duration"),
+		},
+		{
+			input: "15m sideways",
+			err:   errors.New("\"sideways\" is not a valid rounding mode"),
+		},
+	} {
+		var rounding TimeRounding
+		err := rounding.Set(test.input)
+		if err != nil && test.err != nil {
+			if !strings.Contains(err.Error(), test.err.Error()) {
+				t.Errorf("parsing TimeRounding \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err.Error(), err.Error())
+			}
+		} else if err == nil && test.err != nil {
+			t.Errorf("parsing TimeRounding \"%s\" does not return the expected error: \"%s\"", test.input, test.err.Error())
+		} else if err != nil && test.err == nil {
+			t.Errorf("parsing TimeRounding \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		} else {
+			if rounding != test.out {
+				t.Errorf("expected output (%v) does not match actual output: %v", test.out, rounding)
+			}
+			if rounding.String() != test.str || rounding.Describe() != test.describe {
+				t.Errorf("TimeRounding \"%s\" should be printed as (\"%s\", \"%s\"), not (\"%s\", \"%s\")", test.input, test.str, test.describe, rounding.String(), rounding.Describe())
+			}
+			for d, expected := range test.rounded {
+				if actual := rounding.Round(d); actual != expected {
+					t.Errorf("TimeRounding \"%s\" should round %v To %v, not %v", test.input, d, expected, actual)
+				}
+			}
+		}
+	}
+}
+
+// separatedString is a string that is generated by testing/quick From an alphabet that is mostly separators, quotes
+// and escapes.
+type separatedString string
+
+func (separatedString) Generate(rand *rand.Rand, size int) reflect.Value {
+	alphabet := []rune("ab ,;|:\"\\é")
+	runes := make([]rune, rand.Intn(size))
+	for i := range runes {
+		runes[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return reflect.ValueOf(separatedString(runes))
+}
+
+func TestContactFormat(t *testing.T) {
+	roundTrip := func(company, first, last, phone separatedString, address []separatedString, vat bool) bool {
+		contact := Contact{
+			Company:   "Company " + string(company),
+			FirstName: string(first),
+			LastName:  string(last),
+			Email:     "johnsmith@example.com",
+			PhoneNo:   string(phone),
+			Address:   []string{"UK"},
+		}
+		for _, line := range address {
+			contact.Address = append(contact.Address, string(line))
+		}
+		if vat {
+			contact.VATID = "GB123456789"
+		}
+		var parsed Contact
+		if err := parsed.Set(contact.Format()); err != nil {
+			t.Logf("cannot parse formatted contact \"%s\": %s", contact.Format(), err.Error())
+			return false
+		}
+		return reflect.DeepEqual(parsed, contact)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBankFormat(t *testing.T) {
+	roundTrip := func(name separatedString, account uint32, sort uint32) bool {
+		bank := Bank{
+			Bank:      string(name),
+			AccountNo: fmt.Sprintf("%08d", account % 100000000),
+			SortCode:  fmt.Sprintf("%06d", sort % 1000000),
+		}
+		var parsed Bank
+		if err := parsed.Set(bank.Format()); err != nil {
+			t.Logf("cannot parse formatted bank \"%s\": %s", bank.Format(), err.Error())
+			return false
+		}
+		return reflect.DeepEqual(parsed, bank)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestItemsFormat(t *testing.T) {
+	units := []Unit{"", "HUR", "DAY", "KGM"}
+	taxes := []string{"", "20%", "GST 5% (Canada) | QST 9.975% compound (Quebec)"}
+	discounts := []string{"", "10%", "USD 1.50"}
+	roundTrip := func(description, note, group separatedString, thousandths uint32, cents uint32, n uint8, inclusive bool) bool {
+		item := &Item{
+			Description:   string(description),
+			HoursQuantity: Quantity{Value: big.NewRat(int64(thousandths) + 1, 1000)},
+			Unit:          units[int(n) % len(units)],
+			Rate:          Money{int64(cents), UnitedStatesDollar},
+			TaxInclusive:  inclusive,
+			SKU:           string(description + note),
+			Note:          string(note),
+			Group:         string(group),
+		}
+		if tax := taxes[int(n) % len(taxes)]; tax != "" {
+			_ = item.Tax.Set(tax)
+		}
+		if discount := discounts[int(n) % len(discounts)]; discount != "" {
+			_ = item.Discount.Set(discount)
+		}
+		if n % 2 == 0 {
+			item.Period = Period{
+				Start: Date(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)),
+				End:   Date(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)),
+			}
+		}
+		items := Items{item, item}
+		parsed := make(Items, 0)
+		if err := parsed.Set(items.Format()); err != nil {
+			t.Logf("cannot parse formatted items \"%s\": %s", items.Format(), err.Error())
+			return false
+		}
+		return reflect.DeepEqual(parsed, items)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestItemsSeparators(t *testing.T) {
+	config := *DefaultParserConfig
+	if err := config.Separators.Set("; | / ="); err != nil {
+		t.Fatalf("setting separators returned an error: %s", err.Error())
+	}
+	config.Locale = LocaleFromName("de-DE")
+
+	items := make(Items, 0)
+	if err := items.SetWith(&config, "d= Did thing, again| h= 1,5| r= 1.234,50 €| tax= GST 5% / QST 9.975% compound; d= Other thing| r= 10,00 €"); err != nil {
+		t.Fatalf("parsing items with custom separators returned an error: %s", err.Error())
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if item := items[0]; item.Description != "Did thing, again" || item.HoursQuantity.String() != "1.5" || item.Rate.Money != 123450 || len(item.Tax) != 2 {
+		t.Errorf("expected \"Did thing, again\" for 1.5 hours at EUR 1234.50 with 2 taxes, got \"%s\" for %s hours at %d with %d taxes", item.Description, item.HoursQuantity.String(), item.Rate.Money, len(item.Tax))
+	}
+	if item := items[1]; item.Description != "Other thing" || item.Rate.Money != 1000 {
+		t.Errorf("expected \"Other thing\" at EUR 10.00, got \"%s\" at %d", item.Description, item.Rate.Money)
+	}
+
+	parsed := make(Items, 0)
+	if err := parsed.SetWith(&config, items.FormatWith(&config)); err != nil {
+		t.Fatalf("cannot parse formatted items \"%s\": %s", items.FormatWith(&config), err.Error())
+	}
+	if !reflect.DeepEqual(parsed, items) {
+		t.Errorf("formatted items \"%s\" do not parse back To the same items", items.FormatWith(&config))
+	}
+
+	// The DefaultParserConfig is not used or changed by SetWith
+	if DefaultParserConfig.Separators != globals.DefaultSeparators() || DefaultParserConfig.Locale.Name != "default" {
+		t.Errorf("setting items with a ParserConfig changed the DefaultParserConfig To: %v", DefaultParserConfig.Separators)
+	}
+	if err := parsed.Set("d: Did thing; r: EUR 10, d: Other thing; r: EUR 5"); err != nil || len(parsed) != 2 {
+		t.Errorf("parsing items with the DefaultParserConfig after using a ParserConfig should give 2 items, got %d (%v)", len(parsed), err)
+	}
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuantityPrecision is the maximum number of decimal places that a Quantity can be given To.
+var QuantityPrecision uint = 3
+
+var (
+	quantityRegex = regexp.MustCompile(`^[0-9]+(?:\.([0-9]+))?$`)
+	clockRegex    = regexp.MustCompile(`^([0-9]+):([0-5][0-9])(?::([0-5][0-9]))?$`)
+	unitCodeRegex = regexp.MustCompile(`^[A-Z0-9]{2,3}$`)
+)
+
+// TimeRounding determines how a duration is rounded before it is converted into a Quantity of hours.
+type TimeRounding struct {
+	// Increment is the duration that durations are rounded To a multiple of. Durations are not rounded if this is 0.
+	Increment time.Duration
+	// Mode is the RoundingMode used To round To the Increment. RoundDefault behaves like RoundHalfUp.
+	Mode      RoundingMode
+}
+
+// DefaultTimeRounding is the TimeRounding used when a Quantity is given as a duration. Its initial value rounds To the
+// nearest minute.
+var DefaultTimeRounding = &TimeRounding{Increment: time.Minute, Mode: RoundHalfUp}
+
+// Round rounds the given duration To a multiple of the Increment.
+func (r *TimeRounding) Round(d time.Duration) time.Duration {
+	if r.Increment <= 0 {
+		return d
+	}
+	n := r.Mode.Round(big.NewRat(int64(d), int64(r.Increment)))
+	return time.Duration(n.Int64()) * r.Increment
+}
+
+// increment returns the Increment as a human-readable string (e.g. "6 minutes").
+func (r *TimeRounding) increment() string {
+	switch {
+	case r.Increment % time.Hour == 0:
+		return pluralise(int64(r.Increment / time.Hour), "hour")
+	case r.Increment % time.Minute == 0:
+		return pluralise(int64(r.Increment / time.Minute), "minute")
+	default:
+		return r.Increment.String()
+	}
+}
+
+// Describe returns the TimeRounding as a sentence that is printed on an Invoice (e.g. "Time is rounded To the nearest
+// 6 minutes.").
+func (r *TimeRounding) Describe() string {
+	switch {
+	case r.Increment <= 0:
+		return "Time is not rounded."
+	case r.Mode == RoundUp:
+		return fmt.Sprintf("Time is rounded up to the next %s.", r.increment())
+	case r.Mode == RoundDown:
+		return fmt.Sprintf("Time is rounded down to the previous %s.", r.increment())
+	default:
+		return fmt.Sprintf("Time is rounded to the nearest %s.", r.increment())
+	}
+}
+
+// String returns the TimeRounding in the same format that it is parsed From (e.g. "15m up").
+func (r *TimeRounding) String() string {
+	// time.Duration.String always gives the minutes and seconds (e.g. "1h0m0s") so we remove them if they are zero
+	increment := r.Increment.String()
+	if strings.HasSuffix(increment, "m0s") {
+		increment = strings.TrimSuffix(increment, "0s")
+	}
+	if strings.HasSuffix(increment, "h0m") {
+		increment = strings.TrimSuffix(increment, "0m")
+	}
+	if r.Mode == RoundDefault || r.Mode == RoundHalfUp {
+		return increment
+	}
+	return increment + " " + r.Mode.String()
+}
+
+// Set the TimeRounding From a duration, which can be followed by a RoundingMode. Giving a duration of 0 turns off
+// rounding.
+//
+// A valid string value can be:
+//  6m
+// Or:
+//  15m up
+func (r *TimeRounding) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid time rounding, time rounding is given as a duration followed by an optional rounding mode (e.g. 15m up)", value))
+	}
+	increment, err := time.ParseDuration(fields[0])
+	if err != nil || increment < 0 {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid time rounding, \"%s\" is not a valid duration (e.g. 6m)", value, fields[0]))
+	}
+	rounding := TimeRounding{Increment: increment, Mode: RoundHalfUp}
+	if len(fields) == 2 {
+		if err = rounding.Mode.Set(fields[1]); err != nil {
+			return errors.New(fmt.Sprintf("\"%s\" is not a valid time rounding: %s", value, err.Error()))
+		}
+	}
+	*r = rounding
+	return nil
+}
+
+// parseDuration parses either a Go-style duration (e.g. "1h30m") or a clock duration (e.g. "01:45" or "01:45:30").
+func parseDuration(value string) (time.Duration, bool) {
+	if match := clockRegex.FindStringSubmatch(value); match != nil {
+		hours, _ := strconv.ParseInt(match[1], 10, 64)
+		minutes, _ := strconv.ParseInt(match[2], 10, 64)
+		seconds, _ := strconv.ParseInt("0" + match[3], 10, 64)
+		return time.Duration(hours) * time.Hour + time.Duration(minutes) * time.Minute + time.Duration(seconds) * time.Second, true
+	}
+	d, err := time.ParseDuration(value)
+	return d, err == nil && d >= 0
+}
+
+// roundPlaces rounds the given rational To the given number of decimal places using RoundHalfUp.
+func roundPlaces(x *big.Rat, places uint) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := RoundHalfUp.Round(new(big.Rat).Mul(x, new(big.Rat).SetInt(scale)))
+	return new(big.Rat).SetFrac(scaled, scale)
+}
+
+// maxDecimalPlaces is the maximum number of decimal places that decimalPlaces looks for.
+const maxDecimalPlaces = 18
+
+// decimalPlaces returns the number of decimal places needed To write the given rational exactly as a decimal. False is
+// returned if it cannot be written using at most maxDecimalPlaces decimal places (e.g. 1/3).
+func decimalPlaces(x *big.Rat) (int, bool) {
+	scaled := new(big.Rat).Set(x)
+	for places := 0; places <= maxDecimalPlaces; places++ {
+		if scaled.IsInt() {
+			return places, true
+		}
+		scaled.Mul(scaled, big.NewRat(10, 1))
+	}
+	return 0, false
+}
+
+// pluralise returns the given count followed by the given noun, which is pluralised if the count is not 1.
+func pluralise(count int64, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// Quantity is the number of units of an Item, which can be a decimal such as 7.5 hours or 0.25 days.
+type Quantity struct {
+	Value    *big.Rat
+	// Rounding is the TimeRounding used if the Quantity was given as a duration, otherwise it is nil.
+	Rounding *TimeRounding
+}
+
+// NewQuantity returns a Quantity of the given whole number of units.
+func NewQuantity(value int64) Quantity {
+	return Quantity{Value: big.NewRat(value, 1)}
+}
+
+// rat returns the Value of the Quantity, which is zero if the Quantity has not been set.
+func (q *Quantity) rat() *big.Rat {
+	if q.Value == nil {
+		return new(big.Rat)
+	}
+	return q.Value
+}
+
+// IsZero returns whether the Quantity has not been set or is zero.
+func (q *Quantity) IsZero() bool {
+	return q.rat().Sign() == 0
+}
+
+// places returns the number of decimal places needed To write the Quantity exactly. Quantities are always given as
+// decimals, but QuantityPrecision decimal places are used for any that cannot be written exactly.
+func (q *Quantity) places() int {
+	if places, ok := decimalPlaces(q.rat()); ok {
+		return places
+	}
+	return int(QuantityPrecision)
+}
+
+// decimal returns the Quantity as a decimal using the given decimal separator, without any trailing zeros.
+func (q *Quantity) decimal(separator string) string {
+	s := q.rat().FloatString(q.places())
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return strings.Replace(s, ".", separator, 1)
+}
+
+// String returns the Quantity as a decimal without any trailing zeros (e.g. "7.5").
+func (q *Quantity) String() string {
+	return q.decimal(".")
+}
+
+// format returns the Quantity in the same syntax that it is parsed From by Set. A Quantity that was given as a
+// duration is returned as a duration (e.g. "1h30m0s") that is rounded using its TimeRounding, as the hours may have
+// been rounded To the Precision of the ParserConfig it was set with. Otherwise, it is returned as a decimal.
+func (q *Quantity) format() string {
+	if q.Rounding != nil {
+		nanoseconds := new(big.Rat).Mul(q.rat(), big.NewRat(int64(time.Hour), 1))
+		if nanoseconds.IsInt() && nanoseconds.Num().IsInt64() {
+			return q.Rounding.Round(time.Duration(nanoseconds.Num().Int64())).String()
+		}
+	}
+	return q.String()
+}
+
+// Set the Quantity From a positive decimal with at most QuantityPrecision decimal places. The decimal separator can
+// either be a period or the decimal separator of the Locale of the DefaultParserConfig. The Quantity can also be given
+// as a duration, which is rounded using the DefaultTimeRounding and then converted into hours, which are rounded half
+// up To QuantityPrecision decimal places. A Quantity of 0, or a duration that is rounded To 0, is not valid as it
+// would give an empty line.
+//
+// A valid string value can be:
+//  7.5
+// Or:
+//  1h30m
+// Or:
+//  01:45
+func (q *Quantity) Set(value string) error {
+	return q.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the Quantity like Set, but using the Locale, Precision and TimeRounding of the given ParserConfig
+// instead.
+func (q *Quantity) SetWith(config *ParserConfig, value string) error {
+	value = strings.TrimSpace(value)
+	number := value
+	if decimal := config.locale().Decimal; decimal != "." {
+		number = strings.Replace(number, decimal, ".", 1)
+	}
+	match := quantityRegex.FindStringSubmatch(number)
+	if match == nil {
+		if d, ok := parseDuration(value); ok {
+			rounding := *config.timeRounding()
+			// Durations such as 1h20m are not a whole number of Precision decimal places of an hour, so they are
+			// rounded To make the Quantity that is printed on the invoice the same as the Quantity charged for
+			hours := roundPlaces(big.NewRat(int64(rounding.Round(d)), int64(time.Hour)), config.precision())
+			if hours.Sign() == 0 {
+				return errors.New(fmt.Sprintf("\"%s\" is not a valid quantity, quantities must be greater than 0 but the duration is rounded To 0", value))
+			}
+			*q = Quantity{Value: hours, Rounding: &rounding}
+			return nil
+		}
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid quantity, quantities must be a number that is greater than 0 (e.g. 7.5) or a duration (e.g. 1h30m or 01:45)", value))
+	}
+	if precision := config.precision(); uint(len(match[1])) > precision {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid quantity, quantities can have at most %d decimal places", value, precision))
+	}
+	rat, _ := new(big.Rat).SetString(number)
+	if rat.Sign() == 0 {
+		return errors.New(fmt.Sprintf("\"%s\" is not a valid quantity, quantities must be greater than 0", value))
+	}
+	*q = Quantity{Value: rat}
+	return nil
+}
+
+// Unit is the UN/ECE Recommendation 20 code of the unit of measure that the Quantity of an Item is in (e.g. "HUR" for
+// hours).
+type Unit string
+
+// unitNames are the singular and plural names of common Unit(s).
+var unitNames = map[Unit][2]string{
+	"HUR": {"hour", "hours"},
+	"MIN": {"minute", "minutes"},
+	"DAY": {"day", "days"},
+	"WEE": {"week", "weeks"},
+	"MON": {"month", "months"},
+	"ANN": {"year", "years"},
+	"H87": {"piece", "pieces"},
+	"C62": {"unit", "units"},
+	"KGM": {"kg", "kg"},
+	"GRM": {"g", "g"},
+	"MTR": {"m", "m"},
+	"KMT": {"km", "km"},
+	"LTR": {"l", "l"},
+	"LS":  {"lump sum", "lump sum"},
+}
+
+// unitAliases maps the (lowercase) names that a Unit can be given as To its code.
+var unitAliases = map[string]Unit{
+	"hours": "HUR", "hour": "HUR", "hrs": "HUR", "hr": "HUR", "h": "HUR",
+	"minutes": "MIN", "minute": "MIN", "mins": "MIN",
+	"days": "DAY", "day": "DAY",
+	"weeks": "WEE", "week": "WEE", "wk": "WEE",
+	"months": "MON", "month": "MON",
+	"years": "ANN", "year": "ANN", "yr": "ANN",
+	"pieces": "H87", "piece": "H87", "pcs": "H87", "pc": "H87",
+	"units": "C62", "unit": "C62",
+	"kilograms": "KGM", "kilogram": "KGM", "kg": "KGM",
+	"grams": "GRM", "gram": "GRM", "g": "GRM",
+	"metres": "MTR", "metre": "MTR", "meters": "MTR", "meter": "MTR", "m": "MTR",
+	"kilometres": "KMT", "kilometers": "KMT", "km": "KMT",
+	"litres": "LTR", "liters": "LTR", "litre": "LTR", "liter": "LTR", "l": "LTR",
+	"lump sum": "LS",
+}
+
+// Name returns the name of the Unit for the given Quantity (e.g. "hour" or "hours"). Unit(s) without a name are
+// returned as their code.
+func (u Unit) Name(q *Quantity) string {
+	names, ok := unitNames[u]
+	if !ok {
+		return string(u)
+	}
+	if q.rat().Cmp(big.NewRat(1, 1)) == 0 {
+		return names[0]
+	}
+	return names[1]
+}
+
+// Title returns the header of the quantity column of an Invoice whose items are all in the Unit. This is the plural
+// name of the Unit (e.g. "Hours"), unless the Unit is a symbol or code in which case it is "Quantity (kg)".
+func (u Unit) Title() string {
+	names, ok := unitNames[u]
+	if !ok || names[0] == names[1] {
+		return "Quantity (" + u.Name(&Quantity{}) + ")"
+	}
+	return strings.ToUpper(names[1][:1]) + names[1][1:]
+}
+
+// String returns the UN/ECE Recommendation 20 code of the Unit.
+func (u *Unit) String() string {
+	return string(*u)
+}
+
+// Set the Unit From either one of its names or its UN/ECE Recommendation 20 code.
+//
+// A valid string value can be:
+//  hours
+// Or:
+//  HUR
+func (u *Unit) Set(value string) error {
+	name := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	if code, ok := unitAliases[name]; ok {
+		*u = code
+		return nil
+	}
+	if code := strings.ToUpper(name); unitCodeRegex.MatchString(code) {
+		*u = Unit(code)
+		return nil
+	}
+	return errors.New(fmt.Sprintf("\"%s\" is not a valid unit, units are given as a name (e.g. hours, days, pcs, kg) or a UN/ECE Recommendation 20 code (e.g. HUR)", value))
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// rateDateFormats (const) are the formats that dates within a rate file can be given in.
+var rateDateFormats = []string{"2006-01-02", "2/1/2006"}
+
+// parseRateDate parses a date within a rate file using any of the rateDateFormats.
+func parseRateDate(value string) (Date, error) {
+	value = strings.TrimSpace(value)
+	for _, format := range rateDateFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return Date(t), nil
+		}
+	}
+	return Date{}, errors.New(fmt.Sprintf("\"%s\" is not a valid date, dates must be in YYYY-MM-DD or D/M/YYYY format", value))
+}
+
+// parseRateCurrency finds the Currency with the given abbreviation within a rate file.
+func parseRateCurrency(value string) (Currency, error) {
+	currency := CurrencyFromAbbr(strings.TrimSpace(value))
+	if currency == nil {
+		return ZeroCurrency, errors.New(fmt.Sprintf("no currency with abbreviation: %s", value))
+	}
+	return *currency, nil
+}
+
+// parseRate parses an exchange rate within a rate file as an exact decimal.
+func parseRate(value string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rate.Sign() <= 0 {
+		return nil, errors.New(fmt.Sprintf("\"%s\" is not a valid exchange rate", value))
+	}
+	return rate, nil
+}
+
+// ecbEnvelope is the structure of the ECB euro foreign exchange reference rates XML files. For example:
+//  <gesmes:Envelope>
+//  	<Cube>
+//  		<Cube time="2021-12-10">
+//  			<Cube currency="USD" rate="1.1305"/>
+//  		</Cube>
+//  	</Cube>
+//  </gesmes:Envelope>
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// readECBRates reads ExchangeRates From an ECB reference rates XML file. All rates are From EUR. Historic files can
+// contain currencies that are no longer within ISO 4217, these are skipped.
+func readECBRates(r io.Reader) (ExchangeRates, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	rates := make(ExchangeRates, 0)
+	for _, day := range envelope.Days {
+		date, err := parseRateDate(day.Time)
+		if err != nil {
+			return nil, err
+		}
+		for _, dayRate := range day.Rates {
+			to, err := parseRateCurrency(dayRate.Currency)
+			if err != nil {
+				continue
+			}
+			rate, err := parseRate(dayRate.Rate)
+			if err != nil {
+				return nil, err
+			}
+			rates = append(rates, &ExchangeRate{Euro, to, rate, date})
+		}
+	}
+	return rates, nil
+}
+
+// readCSVRates reads ExchangeRates From a CSV file where each record is in the format:
+//  date,from,to,rate
+// A header record is optional.
+func readCSVRates(r io.Reader) (ExchangeRates, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rates := make(ExchangeRates, 0)
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+		date, err := parseRateDate(record[0])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("record %d: %s", i + 1, err.Error()))
+		}
+		from, err := parseRateCurrency(record[1])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("record %d: %s", i + 1, err.Error()))
+		}
+		to, err := parseRateCurrency(record[2])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("record %d: %s", i + 1, err.Error()))
+		}
+		rate, err := parseRate(record[3])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("record %d: %s", i + 1, err.Error()))
+		}
+		rates = append(rates, &ExchangeRate{from, to, rate, date})
+	}
+	return rates, nil
+}
+
+// ReadRates reads ExchangeRates From either an ECB reference rates XML file or a CSV file of date,from,to,rate
+// records. The format is detected From the first non-whitespace character.
+func ReadRates(r io.Reader) (ExchangeRates, error) {
+	buffered := bufio.NewReader(r)
+	for {
+		char, _, err := buffered.ReadRune()
+		if err != nil {
+			if err == io.EOF {
+				return ExchangeRates{}, nil
+			}
+			return nil, err
+		}
+		if !strings.ContainsRune(" \t\r\n\ufeff", char) {
+			if err = buffered.UnreadRune(); err != nil {
+				return nil, err
+			}
+			if char == '<' {
+				return readECBRates(buffered)
+			}
+			return readCSVRates(buffered)
+		}
+	}
+}
+
+// RateFile is a file-backed RateProvider that reads its ExchangeRates From either an ECB reference rates XML file or a
+// CSV file of date,from,to,rate records.
+type RateFile struct {
+	Path  string
+	Rates ExchangeRates
+}
+
+// Rate returns the ExchangeRate From one Currency To another that is effective on the given Date.
+func (rf *RateFile) Rate(from, to Currency, date Date) (*ExchangeRate, error) {
+	return rf.Rates.Rate(from, to, date)
+}
+
+func (rf *RateFile) String() string {
+	return rf.Path
+}
+
+// Set the RateFile by reading the ExchangeRates within the file at the given path.
+//
+// If the file cannot be read or parsed then an error will be returned otherwise the error will be nil.
+func (rf *RateFile) Set(value string) error {
+	data, err := ioutil.ReadFile(value)
+	if err != nil {
+		return err
+	}
+	rates, err := ReadRates(bytes.NewReader(data))
+	if err != nil {
+		return errors.New(fmt.Sprintf("cannot read exchange rates From \"%s\": %s", value, err.Error()))
+	}
+	rf.Path = value
+	rf.Rates = rates
+	return nil
+}
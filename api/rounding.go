@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// RoundingMode determines how an exact amount is rounded To the nearest minor unit of a Currency.
+type RoundingMode int
+
+const (
+	// RoundDefault uses the RoundingMode set for the Currency on the Invoice (see WithCurrencyRounding), or
+	// RoundHalfUp if none has been set.
+	RoundDefault RoundingMode = iota
+	// RoundHalfUp rounds To the nearest minor unit, rounding halves away From zero.
+	RoundHalfUp
+	// RoundHalfEven rounds To the nearest minor unit, rounding halves To the nearest even minor unit (banker's
+	// rounding).
+	RoundHalfEven
+	// RoundHalfDown rounds To the nearest minor unit, rounding halves towards zero.
+	RoundHalfDown
+	// RoundDown truncates towards zero.
+	RoundDown
+	// RoundUp rounds away From zero.
+	RoundUp
+)
+
+// roundingModeNames (const) maps each RoundingMode To the names that can be used To parse it.
+var roundingModeNames = map[RoundingMode][]string{
+	RoundDefault:  {"default"},
+	RoundHalfUp:   {"half-up", "halfup"},
+	RoundHalfEven: {"half-even", "halfeven", "bankers", "banker's"},
+	RoundHalfDown: {"half-down", "halfdown"},
+	RoundDown:     {"down", "truncate", "trunc"},
+	RoundUp:       {"up", "ceiling"},
+}
+
+// Round rounds the given rational To an integer using the RoundingMode. RoundDefault behaves like RoundHalfUp.
+func (r RoundingMode) Round(x *big.Rat) *big.Int {
+	q, rem := new(big.Int).QuoRem(new(big.Int).Abs(x.Num()), x.Denom(), new(big.Int))
+	// We compare twice the remainder against the denominator To find out whether we are below, at, or above the half
+	half := new(big.Int).Lsh(rem, 1).Cmp(x.Denom())
+	increment := false
+	switch r {
+	case RoundDown:
+	case RoundUp:
+		increment = rem.Sign() != 0
+	case RoundHalfDown:
+		increment = half > 0
+	case RoundHalfEven:
+		increment = half > 0 || half == 0 && q.Bit(0) == 1
+	default:
+		increment = half >= 0
+	}
+	if increment {
+		q.Add(q, big.NewInt(1))
+	}
+	if x.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
+
+func (r RoundingMode) String() string {
+	names, ok := roundingModeNames[r]
+	if !ok {
+		return fmt.Sprintf("RoundingMode(%d)", int(r))
+	}
+	return names[0]
+}
+
+// Set the RoundingMode From the given string value.
+//
+// A valid string value can be one of:
+//  half-up, half-even, half-down, down, up
+func (r *RoundingMode) Set(value string) error {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for mode, names := range roundingModeNames {
+		for _, name := range names {
+			if name == value {
+				*r = mode
+				return nil
+			}
+		}
+	}
+	return errors.New(fmt.Sprintf("\"%s\" is not a valid rounding mode", value))
+}
+
+// CurrencyRoundings maps the abbreviation of a Currency To the RoundingMode used for amounts in that Currency.
+type CurrencyRoundings map[string]RoundingMode
+
+// String returns the CurrencyRoundings in the same format that they are parsed From, ordered by Currency.
+func (cr *CurrencyRoundings) String() string {
+	abbrs := make([]string, 0, len(*cr))
+	for abbr := range *cr {
+		abbrs = append(abbrs, abbr)
+	}
+	sort.Strings(abbrs)
+	roundings := make([]string, len(abbrs))
+	for n, abbr := range abbrs {
+		roundings[n] = abbr + " " + (*cr)[abbr].String()
+	}
+	return strings.Join(roundings, DefaultParserConfig.Separators.FirstLevel + " ")
+}
+
+// Set the CurrencyRoundings From a first level separated list of currencies, each followed by the RoundingMode used for
+// that Currency.
+//
+// A valid string value can be:
+//  CHF half-even, JPY down
+func (cr *CurrencyRoundings) Set(value string) error {
+	return cr.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the CurrencyRoundings like Set, but using the Separators of the given ParserConfig instead.
+func (cr *CurrencyRoundings) SetWith(config *ParserConfig, value string) error {
+	roundings := make(CurrencyRoundings)
+	for _, roundingStr := range config.Separators.FirstLevelSplit().Split(value, -1) {
+		fields := strings.Fields(roundingStr)
+		if len(fields) != 2 {
+			return errors.New(fmt.Sprintf("\"%s\" is not a valid currency rounding, each currency rounding is given as a currency abbreviation followed by a rounding mode (e.g. CHF half-even)", strings.TrimSpace(roundingStr)))
+		}
+		var currency Currency
+		if err := currency.Set(fields[0]); err != nil {
+			return err
+		}
+		if _, ok := roundings[currency.Abbr]; ok {
+			return errors.New(fmt.Sprintf("the rounding mode of %s is given more than once", currency.Abbr))
+		}
+		var mode RoundingMode
+		if err := mode.Set(fields[1]); err != nil {
+			return err
+		}
+		roundings[currency.Abbr] = mode
+	}
+	*cr = roundings
+	return nil
+}
+
+// Allocate splits the Money value into parts proportional To the given ratios without losing any minor units. Minor
+// units that cannot be split evenly are given To the parts with the largest remainders, ties going To the earliest
+// part. Negative values are allocated as if they were positive and then each part is negated.
+//
+// For example, allocating GBP 100.00 using the ratios 1, 1, 1 gives GBP 33.34, GBP 33.33 and GBP 33.33.
+func (m *Money) Allocate(ratios ...uint) ([]*Money, error) {
+	total := new(big.Int)
+	for _, ratio := range ratios {
+		total.Add(total, new(big.Int).SetUint64(uint64(ratio)))
+	}
+	if total.Sign() == 0 {
+		return nil, errors.New("cannot allocate Money using ratios that sum To zero")
+	}
+
+	parts := make([]*Money, len(ratios))
+	remainders := make([]*big.Int, len(ratios))
+	order := make([]int, len(ratios))
+	amount := m.Abs().Money
+	allocated := int64(0)
+	for i, ratio := range ratios {
+		q, rem := new(big.Int).QuoRem(
+			new(big.Int).Mul(big.NewInt(amount), new(big.Int).SetUint64(uint64(ratio))),
+			total,
+			new(big.Int),
+		)
+		parts[i] = &Money{q.Int64(), m.Currency}
+		remainders[i] = rem
+		order[i] = i
+		allocated += q.Int64()
+	}
+
+	// Hand out the leftover minor units one at a time
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	for i := int64(0); i < amount - allocated; i++ {
+		parts[order[i]].Money++
+	}
+
+	if m.IsNegative() {
+		for _, part := range parts {
+			part.Money = -part.Money
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,538 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/andygello555/ginvoice/globals"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TaxRate is the percentage of tax that is charged on top of the net amount of an Item.
+type TaxRate struct {
+	// Name is the name of the TaxRate if it was given as one of the named TaxRates (e.g. "standard").
+	Name    string
+	// Percent is the percentage of the net amount that is charged as tax. A nil Percent is treated as 0%.
+	Percent *big.Rat
+}
+
+var (
+	// TaxRates contains the named TaxRate(s) that can be given instead of a percentage, mapped by their lowercase
+	// name. The initial values are the UK VAT rates, more can be added or existing ones changed before parsing items.
+	TaxRates = map[string]TaxRate{
+		"standard": {"standard", big.NewRat(20, 1)},
+		"reduced":  {"reduced", big.NewRat(5, 1)},
+		"zero":     {"zero", big.NewRat(0, 1)},
+	}
+)
+
+// percent returns the Percent of the TaxRate, or 0 if it is nil.
+func (tr *TaxRate) percent() *big.Rat {
+	if tr.Percent == nil {
+		return new(big.Rat)
+	}
+	return tr.Percent
+}
+
+// IsZero returns whether the TaxRate is 0%.
+func (tr *TaxRate) IsZero() bool {
+	return tr.percent().Sign() == 0
+}
+
+// Tax returns the tax charged on the given net amount, rounded To the nearest minor unit using the given
+// RoundingMode.
+func (tr *TaxRate) Tax(net *Money, mode RoundingMode) *Money {
+	return net.MulRounded(new(big.Rat).Quo(tr.percent(), big.NewRat(100, 1)), mode)
+}
+
+// Net returns the net amount of the given tax-inclusive gross amount, rounded To the nearest minor unit using the
+// given RoundingMode. The tax is the difference between the gross and the net amount.
+func (tr *TaxRate) Net(gross *Money, mode RoundingMode) *Money {
+	return gross.MulRounded(tr.inclusiveFactor(), mode)
+}
+
+// inclusiveFactor returns 100 / (100 + Percent), which is the fraction of a tax-inclusive amount that is net.
+func (tr *TaxRate) inclusiveFactor() *big.Rat {
+	return new(big.Rat).Quo(big.NewRat(100, 1), new(big.Rat).Add(big.NewRat(100, 1), tr.percent()))
+}
+
+// splitGross finds the net amount of each of the given tax-inclusive gross amounts following the EN 16931 convention:
+// the tax of the TaxRate is calculated on the sum of the net amounts, and the sum of the net amounts plus the tax must
+// equal the sum of the gross amounts.
+//
+// The total net amount is chosen so that it plus its rounded tax equals the total gross amount. This is not always
+// possible (e.g. a gross of 0.01 at 20%), in which case the tax absorbs the difference. The total net amount is then
+// distributed between the gross amounts by rounding each To the nearest minor unit and then giving the minor units
+// left over To the amounts with the largest rounding error, so that each net amount is as close as possible To its
+// exact value.
+func (tr *TaxRate) splitGross(grosses []*Money, mode RoundingMode) []*Money {
+	nets := make([]*Money, len(grosses))
+	if len(grosses) == 0 {
+		return nets
+	}
+	total := &Money{0, grosses[0].Currency}
+	for _, gross := range grosses {
+		total = total.Add(gross)
+	}
+	taxRate := new(big.Rat).Quo(tr.percent(), big.NewRat(100, 1))
+
+	// Find the total net amount that reconciles with the total gross amount
+	totalNet := tr.Net(total, mode)
+	for _, candidate := range []int64{totalNet.Money, totalNet.Money - 1, totalNet.Money + 1} {
+		net := &Money{candidate, total.Currency}
+		if net.Add(net.MulRounded(taxRate, mode)).Money == total.Money {
+			totalNet = net
+			break
+		}
+	}
+
+	// Round each net amount and work out how far each one is From its exact value
+	factor := tr.inclusiveFactor()
+	errs := make([]*big.Rat, len(grosses))
+	remaining := totalNet.Money
+	for n, gross := range grosses {
+		exact := new(big.Rat).Mul(new(big.Rat).SetInt64(gross.Money), factor)
+		nets[n] = &Money{mode.Round(exact).Int64(), gross.Currency}
+		errs[n] = exact.Sub(exact, new(big.Rat).SetInt64(nets[n].Money))
+		remaining -= nets[n].Money
+	}
+
+	// Then hand out the left over minor units one at a time
+	order := make([]int, len(grosses))
+	for n := range order {
+		order[n] = n
+	}
+	for remaining != 0 {
+		step := int64(1)
+		if remaining < 0 {
+			step = -1
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			if step > 0 {
+				return errs[order[a]].Cmp(errs[order[b]]) > 0
+			}
+			return errs[order[a]].Cmp(errs[order[b]]) < 0
+		})
+		n := order[0]
+		nets[n].Money += step
+		errs[n].Sub(errs[n], big.NewRat(step, 1))
+		remaining -= step
+	}
+	return nets
+}
+
+// String returns the TaxRate as a percentage without any trailing zeros (e.g. "20%" or "7.7%").
+func (tr *TaxRate) String() string {
+	percent := tr.percent()
+	if percent.IsInt() {
+		return percent.Num().String() + "%"
+	}
+	return strings.TrimRight(strings.TrimRight(percent.FloatString(4), "0"), ".") + "%"
+}
+
+// Set the TaxRate From either a percentage or the name of one of the TaxRates (case insensitive).
+//
+// A valid string value can be:
+//  20%
+// Or:
+//  standard
+func (tr *TaxRate) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if named, ok := TaxRates[strings.ToLower(value)]; ok {
+		*tr = TaxRate{named.Name, new(big.Rat).Set(named.percent())}
+		return nil
+	}
+
+	if strings.HasSuffix(value, "%") {
+		if percent, ok := new(big.Rat).SetString(strings.TrimSpace(strings.TrimSuffix(value, "%"))); ok && percent.Sign() >= 0 {
+			*tr = TaxRate{Percent: percent}
+			return nil
+		}
+	}
+
+	return taxRateError(value)
+}
+
+// taxRateError returns the error for when the given value cannot be parsed as a TaxRate.
+func taxRateError(value string) error {
+	names := make([]string, 0, len(TaxRates))
+	for name := range TaxRates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return errors.New(fmt.Sprintf("\"%s\" is not a valid tax rate, tax rates are given as a percentage (e.g. 20%%) or one of the named rates: %s", value, strings.Join(names, ", ")))
+}
+
+// TaxComponent is one of the taxes charged on an Item, such as a federal or state tax.
+type TaxComponent struct {
+	// Name is the name of the tax (e.g. "GST"). This is optional.
+	Name         string
+	Rate         TaxRate
+	// Compound is whether the tax is charged on the net amount plus all the taxes before it, rather than on just the
+	// net amount.
+	Compound     bool
+	// Jurisdiction is the place that levies the tax (e.g. "Quebec"). This is optional.
+	Jurisdiction string
+}
+
+// taxJurisdictionRegex (const) matches the jurisdiction given in parentheses at the end of a TaxComponent.
+var taxJurisdictionRegex = regexp.MustCompile(`\(([^()]*)\)\s*$`)
+
+// String returns the TaxComponent in the same format that it is parsed From (e.g. "QST 9.975% compound (Quebec)").
+func (tc *TaxComponent) String() string {
+	parts := make([]string, 0)
+	if tc.Name != "" {
+		parts = append(parts, tc.Name)
+	}
+	parts = append(parts, tc.Rate.String())
+	if tc.Compound {
+		parts = append(parts, "compound")
+	}
+	if tc.Jurisdiction != "" {
+		parts = append(parts, "(" + tc.Jurisdiction + ")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Set the TaxComponent From a TaxRate that is optionally preceded by a name, and optionally followed by "compound"
+// and a jurisdiction in parentheses.
+//
+// A valid string value can be:
+//  20%
+// Or:
+//  QST 9.975% compound (Quebec)
+func (tc *TaxComponent) Set(value string) error {
+	component := TaxComponent{}
+	rest := strings.TrimSpace(value)
+	if match := taxJurisdictionRegex.FindStringSubmatch(rest); match != nil {
+		component.Jurisdiction = strings.TrimSpace(match[1])
+		rest = strings.TrimSpace(rest[:len(rest) - len(match[0])])
+	}
+	words := strings.Fields(rest)
+	if len(words) > 1 && strings.EqualFold(words[len(words) - 1], "compound") {
+		component.Compound = true
+		words = words[:len(words) - 1]
+	}
+	if len(words) == 0 {
+		return taxRateError(value)
+	}
+	// The tax rate can contain a space before the percent sign (e.g. "7.7 %")
+	rateStart := len(words) - 1
+	if words[rateStart] == "%" && rateStart > 0 {
+		rateStart--
+	}
+	if err := component.Rate.Set(strings.Join(words[rateStart:], " ")); err != nil {
+		return taxRateError(strings.TrimSpace(value))
+	}
+	component.Name = strings.Join(words[:rateStart], " ")
+	*tc = component
+	return nil
+}
+
+// Taxes is the list of TaxComponent(s) charged on an Item, in the order that they are applied.
+type Taxes []*TaxComponent
+
+// Rate returns the combined TaxRate of all the TaxComponent(s) as a percentage of the net amount, taking into account
+// any compound taxes. If there is only one TaxComponent then its TaxRate is returned.
+func (ts Taxes) Rate() TaxRate {
+	if len(ts) == 1 {
+		return ts[0].Rate
+	}
+	total := new(big.Rat)
+	for _, component := range ts {
+		base := big.NewRat(100, 1)
+		if component.Compound {
+			base.Add(base, total)
+		}
+		total.Add(total, base.Mul(base, new(big.Rat).Quo(component.Rate.percent(), big.NewRat(100, 1))))
+	}
+	return TaxRate{Percent: total}
+}
+
+// IsZero returns whether all the TaxComponent(s) are 0%.
+func (ts Taxes) IsZero() bool {
+	for _, component := range ts {
+		if !component.Rate.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// Named returns whether any of the TaxComponent(s) have a name or jurisdiction.
+func (ts Taxes) Named() bool {
+	for _, component := range ts {
+		if component.Name != "" || component.Jurisdiction != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Tax returns the tax charged by each TaxComponent on the given net amount, each rounded To the nearest minor unit
+// using the given RoundingMode. Compound taxes are charged on the net amount plus the rounded taxes before them.
+func (ts Taxes) Tax(net *Money, mode RoundingMode) []*Money {
+	taxes := make([]*Money, len(ts))
+	prior := &Money{0, net.Currency}
+	for n, component := range ts {
+		base := net
+		if component.Compound {
+			base = net.Add(prior)
+		}
+		taxes[n] = component.Rate.Tax(base, mode)
+		prior = prior.Add(taxes[n])
+	}
+	return taxes
+}
+
+// split divides the total tax of an Item, that has the given net amount, between each TaxComponent. The tax of each
+// TaxComponent is calculated using Tax, then the last TaxComponent is adjusted by any difference between the sum and
+// the total tax, which can be caused by rounding when the tax has been calculated backwards From a gross amount.
+func (ts Taxes) split(net, tax *Money, mode RoundingMode) []*Money {
+	taxes := ts.Tax(net, mode)
+	if len(taxes) == 0 {
+		return taxes
+	}
+	last := len(taxes) - 1
+	taxes[last] = tax.Sub(sumMoney(net.Currency, taxes[:last]...))
+	return taxes
+}
+
+// Rates returns the TaxRate of each TaxComponent separated by " + " (e.g. "5% + 9.975%").
+func (ts Taxes) Rates() string {
+	if len(ts) == 0 {
+		return (&TaxRate{}).String()
+	}
+	rates := make([]string, len(ts))
+	for n, component := range ts {
+		rates[n] = component.Rate.String()
+	}
+	return strings.Join(rates, " + ")
+}
+
+// String returns each TaxComponent separated by the third level separator (e.g. "GST 5% | QST 9.975% compound").
+func (ts *Taxes) String() string {
+	if len(*ts) == 0 {
+		return (&TaxRate{}).String()
+	}
+	components := make([]string, len(*ts))
+	for n, component := range *ts {
+		components[n] = component.String()
+	}
+	return strings.Join(components, " " + DefaultParserConfig.Separators.ThirdLevel + " ")
+}
+
+// format returns the Taxes in the same syntax that they are parsed From by set, with each TaxComponent separated by
+// the given separator. Each TaxComponent is quoted if it contains one of the Separators of the given ParserConfig.
+func (ts *Taxes) format(config *ParserConfig, sep string) string {
+	components := make([]string, len(*ts))
+	for n, component := range *ts {
+		components[n] = config.Separators.Quote(component.String())
+	}
+	return strings.Join(components, " " + sep + " ")
+}
+
+// Set the Taxes From a list of TaxComponent(s) separated by the third level separator. Each TaxComponent can be
+// quoted so that it can contain a separator.
+//
+// A valid string value can be:
+//  standard
+// Or:
+//  GST 5% (Canada) | QST 9.975% compound (Quebec)
+func (ts *Taxes) Set(value string) error {
+	return ts.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the Taxes like Set, but TaxComponent(s) are separated by the third level separator of the given
+// ParserConfig.
+func (ts *Taxes) SetWith(config *ParserConfig, value string) error {
+	return ts.set(value, config.Separators.ThirdLevelSplit())
+}
+
+// set the Taxes From a list of TaxComponent(s) that is split using the given Splitter.
+func (ts *Taxes) set(value string, split *globals.Splitter) error {
+	taxes := make(Taxes, 0)
+	for _, componentStr := range split.Split(value, -1) {
+		componentStr, err := globals.Unquote(componentStr)
+		if err != nil {
+			return err
+		}
+		component := &TaxComponent{}
+		if err = component.Set(componentStr); err != nil {
+			return err
+		}
+		taxes = append(taxes, component)
+	}
+	*ts = taxes
+	return nil
+}
+
+// sumMoney returns the sum of the given Money values in the given Currency.
+func sumMoney(currency Currency, ms ...*Money) *Money {
+	total := &Money{0, currency}
+	for _, m := range ms {
+		total = total.Add(m)
+	}
+	return total
+}
+
+// TaxComponentTotal is the total tax charged by all the TaxComponent(s) of the items of an Invoice that share the
+// same name, TaxRate, compounding and jurisdiction.
+type TaxComponentTotal struct {
+	Component TaxComponent
+	Tax       *Money
+}
+
+// TaxSummaryLine is the total net amount, tax and gross amount of all the items of an Invoice that share a
+// TaxCategory and TaxRate.
+type TaxSummaryLine struct {
+	Category TaxCategory
+	Rate     TaxRate
+	Net      *Money
+	Tax      *Money
+	Gross    *Money
+}
+
+// TaxSummary is the tax analysis of an Invoice. It contains a TaxSummaryLine for each TaxCategory and TaxRate used by
+// the items of the Invoice, in the order that each is first used, as well as the Net, Tax and Gross totals of the
+// Invoice.
+type TaxSummary struct {
+	Lines      []*TaxSummaryLine
+	// Components is the total of each TaxComponent, in the order that each is first used.
+	Components []*TaxComponentTotal
+	Net        *Money
+	Tax        *Money
+	Gross      *Money
+}
+
+// add the given net amount and tax of an item with the given TaxCategory and Taxes To the TaxSummary. The tax charged by
+// each TaxComponent is also given.
+func (ts *TaxSummary) add(category TaxCategory, taxes Taxes, net, tax *Money, componentTaxes []*Money) {
+	for n, component := range taxes {
+		var total *TaxComponentTotal
+		for _, t := range ts.Components {
+			if t.Component.Name == component.Name && t.Component.Jurisdiction == component.Jurisdiction && t.Component.Compound == component.Compound && t.Component.Rate.percent().Cmp(component.Rate.percent()) == 0 {
+				total = t
+				break
+			}
+		}
+		if total == nil {
+			total = &TaxComponentTotal{*component, &Money{0, net.Currency}}
+			ts.Components = append(ts.Components, total)
+		}
+		total.Tax = total.Tax.Add(componentTaxes[n])
+	}
+
+	rate := taxes.Rate()
+	var line *TaxSummaryLine
+	for _, l := range ts.Lines {
+		if l.Category == category && l.Rate.percent().Cmp(rate.percent()) == 0 {
+			line = l
+			break
+		}
+	}
+	if line == nil {
+		line = &TaxSummaryLine{
+			Category: category,
+			Rate:     rate,
+			Net:      &Money{0, net.Currency},
+			Tax:      &Money{0, net.Currency},
+			Gross:    &Money{0, net.Currency},
+		}
+		ts.Lines = append(ts.Lines, line)
+	}
+	gross := net.Add(tax)
+	line.Net = line.Net.Add(net)
+	line.Tax = line.Tax.Add(tax)
+	line.Gross = line.Gross.Add(gross)
+	ts.Net = ts.Net.Add(net)
+	ts.Tax = ts.Tax.Add(tax)
+	ts.Gross = ts.Gross.Add(gross)
+}
+
+// TaxCategory is a UNCL5305 tax category code, which determines how tax is treated for an Item.
+type TaxCategory string
+
+const (
+	// TaxStandard is for items that are taxed at a non-zero TaxRate.
+	TaxStandard TaxCategory = "S"
+	// TaxZeroRated is for items that are taxable but at a rate of 0%.
+	TaxZeroRated TaxCategory = "Z"
+	// TaxExempt is for items that are exempt From tax. An exemption reason must be given.
+	TaxExempt TaxCategory = "E"
+	// TaxReverseCharge is for items where the buyer is liable To account for the tax. The VAT IDs of both the seller
+	// and the buyer must be given.
+	TaxReverseCharge TaxCategory = "AE"
+	// TaxIntraCommunity is for items that are supplied To a buyer in another EU member state and so are exempt From
+	// tax. The VAT IDs of both the seller and the buyer must be given.
+	TaxIntraCommunity TaxCategory = "K"
+	// TaxExport is for items that are exported outside the EU and so are exempt From tax.
+	TaxExport TaxCategory = "G"
+	// TaxOutOfScope is for items that are not subject To tax.
+	TaxOutOfScope TaxCategory = "O"
+)
+
+var (
+	// taxCategoryNames (const) maps each TaxCategory To its description followed by the names that can be used To
+	// parse it, in addition To its code.
+	taxCategoryNames = map[TaxCategory][]string{
+		TaxStandard:       {"Standard rated", "standard"},
+		TaxZeroRated:      {"Zero rated", "zero", "zero-rated"},
+		TaxExempt:         {"Exempt from tax", "exempt"},
+		TaxReverseCharge:  {"Reverse charge", "reverse-charge", "reverse"},
+		TaxIntraCommunity: {"Intra-community supply", "intra-community", "intra"},
+		TaxExport:         {"Export outside the EU", "export"},
+		TaxOutOfScope:     {"Outside the scope of tax", "out-of-scope", "outside"},
+	}
+	// taxCategoryLegends (const) is the wording that must be printed on an invoice that contains items of each
+	// TaxCategory.
+	taxCategoryLegends = map[TaxCategory]string{
+		TaxExempt:         "Exempt from VAT",
+		TaxReverseCharge:  "Reverse charge: the customer is liable to account for the VAT on this supply (Article 196, Council Directive 2006/112/EC)",
+		TaxIntraCommunity: "Intra-community supply exempt from VAT (Article 138, Council Directive 2006/112/EC)",
+		TaxExport:         "Export outside the EU exempt from VAT (Article 146, Council Directive 2006/112/EC)",
+		TaxOutOfScope:     "Outside the scope of VAT",
+	}
+)
+
+// Description returns the human-readable description of the TaxCategory (e.g. "Reverse charge").
+func (tc *TaxCategory) Description() string {
+	if names, ok := taxCategoryNames[*tc]; ok {
+		return names[0]
+	}
+	return string(*tc)
+}
+
+// zeroRate returns whether items in the TaxCategory must have a TaxRate of 0%.
+func (tc *TaxCategory) zeroRate() bool {
+	return *tc != TaxStandard
+}
+
+func (tc *TaxCategory) String() string {
+	return string(*tc)
+}
+
+// Set the TaxCategory From either its UNCL5305 code or its name (case insensitive).
+//
+// A valid string value can be one of:
+//  S, Z, E, AE, K, G, O
+// Or:
+//  standard, zero, exempt, reverse-charge, intra-community, export, out-of-scope
+func (tc *TaxCategory) Set(value string) error {
+	value = strings.TrimSpace(value)
+	for category, names := range taxCategoryNames {
+		if strings.EqualFold(string(category), value) {
+			*tc = category
+			return nil
+		}
+		for _, name := range names[1:] {
+			if strings.EqualFold(name, value) {
+				*tc = category
+				return nil
+			}
+		}
+	}
+	return errors.New(fmt.Sprintf("\"%s\" is not a valid tax category, tax categories are given as one of the UNCL5305 codes: S, Z, E, AE, K, G, O", value))
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/andygello555/ginvoice/globals"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// daysInYear is the number of days that an annual InterestRate is spread over.
+const daysInYear = 365
+
+var (
+	termsNetRegex          = regexp.MustCompile(`(?i)^(?:([0-9.]+) ?%?/([0-9]+))? *(?:net ([0-9]+))?$`)
+	termsInterestRegex     = regexp.MustCompile(`(?i)^interest (.+)$`)
+	termsCompensationRegex = regexp.MustCompile(`(?i)^compensation (.+)$`)
+)
+
+// statutoryCompensation are the fixed sums that can be claimed under the Late Payment of Commercial Debts (Interest)
+// Act 1998, ordered by the size of the debt that they apply up To.
+var statutoryCompensation = []struct{
+	below  int64
+	amount int64
+}{
+	{100000, 4000},
+	{1000000, 7000},
+	{0, 10000},
+}
+
+// PaymentTerms are the terms under which an Invoice is paid. These are given in the usual "2/10 net 30" shorthand,
+// where a 2% discount is given if the Invoice is paid within 10 days and the Invoice is due within 30 days. Interest
+// and a fixed compensation can also be charged if the Invoice is paid after its DueDate.
+type PaymentTerms struct {
+	// DiscountPercent is the percentage taken off the amount payable if the Invoice is paid within DiscountDays of
+	// the InvoiceDate.
+	DiscountPercent       *big.Rat
+	DiscountDays          uint
+	// NetDays is the number of days after the InvoiceDate that the Invoice is due. If this is 0 then the DueDate of
+	// the Invoice is used as is.
+	NetDays               uint
+	// InterestRate is the annual percentage of simple interest charged on the amount payable for each day that it is
+	// paid late.
+	InterestRate          *big.Rat
+	// Compensation is the fixed amount charged if the Invoice is paid late.
+	Compensation          *Money
+	// StatutoryCompensation is whether the fixed sum set out in the Late Payment of Commercial Debts (Interest) Act
+	// 1998 is charged if the Invoice is paid late. This is used instead of Compensation.
+	StatutoryCompensation bool
+}
+
+// IsZero returns whether the PaymentTerms do not change the amount due on any date.
+func (t *PaymentTerms) IsZero() bool {
+	return !t.HasDiscount() && !t.HasLatePaymentCharges() && t.NetDays == 0
+}
+
+// HasDiscount returns whether the PaymentTerms give a discount for early payment.
+func (t *PaymentTerms) HasDiscount() bool {
+	return t.DiscountPercent != nil && t.DiscountPercent.Sign() != 0
+}
+
+// HasLatePaymentCharges returns whether interest or compensation is charged for late payment.
+func (t *PaymentTerms) HasLatePaymentCharges() bool {
+	return t.InterestRate != nil && t.InterestRate.Sign() != 0 || t.Compensation != nil || t.StatutoryCompensation
+}
+
+// Discount returns the early payment discount on the given amount payable, rounded using the given RoundingMode.
+func (t *PaymentTerms) Discount(payable *Money, mode RoundingMode) *Money {
+	if !t.HasDiscount() {
+		return &Money{0, payable.Currency}
+	}
+	return payable.MulRounded(new(big.Rat).Quo(t.DiscountPercent, big.NewRat(100, 1)), mode)
+}
+
+// Interest returns the simple interest charged on the given amount payable when it is paid the given number of days
+// late, rounded using the given RoundingMode.
+func (t *PaymentTerms) Interest(payable *Money, daysLate int, mode RoundingMode) *Money {
+	if t.InterestRate == nil || daysLate <= 0 {
+		return &Money{0, payable.Currency}
+	}
+	factor := new(big.Rat).Mul(t.InterestRate, big.NewRat(int64(daysLate), 100 * daysInYear))
+	return payable.MulRounded(factor, mode)
+}
+
+// CompensationFor returns the fixed compensation charged when the given amount payable is paid late.
+func (t *PaymentTerms) CompensationFor(payable *Money) *Money {
+	switch {
+	case t.StatutoryCompensation:
+		for _, tier := range statutoryCompensation {
+			if tier.below == 0 || payable.Money < tier.below {
+				return &Money{tier.amount, GreatBritishPound}
+			}
+		}
+	case t.Compensation != nil:
+		return t.Compensation
+	}
+	return &Money{0, payable.Currency}
+}
+
+// String returns the PaymentTerms in the same format that they are parsed From (e.g. "2/10 net 30, interest 8%").
+func (t *PaymentTerms) String() string {
+	terms := make([]string, 0)
+	net := make([]string, 0)
+	if t.HasDiscount() {
+		net = append(net, fmt.Sprintf("%s/%d", strings.TrimSuffix(ratPercent(t.DiscountPercent), "%"), t.DiscountDays))
+	}
+	if t.NetDays > 0 {
+		net = append(net, fmt.Sprintf("net %d", t.NetDays))
+	}
+	if len(net) > 0 {
+		terms = append(terms, strings.Join(net, " "))
+	}
+	if t.InterestRate != nil && t.InterestRate.Sign() != 0 {
+		terms = append(terms, "interest " + ratPercent(t.InterestRate))
+	}
+	switch {
+	case t.StatutoryCompensation:
+		terms = append(terms, "compensation statutory")
+	case t.Compensation != nil:
+		terms = append(terms, "compensation " + t.Compensation.StringAbbr())
+	}
+	return strings.Join(terms, DefaultParserConfig.Separators.FirstLevel + " ")
+}
+
+// Set the PaymentTerms From a comma-separated list of terms. The early payment discount and net days are given in the
+// "2/10 net 30" shorthand, where either part can be left out. Interest is given as an annual percentage and
+// compensation is given as either an amount or "statutory".
+//
+// A valid string value can be:
+//  2/10 net 30, interest 13.25%, compensation statutory
+// Or:
+//  net 14, compensation GBP 40
+func (t *PaymentTerms) Set(value string) error {
+	return t.SetWith(DefaultParserConfig, value)
+}
+
+// SetWith sets the PaymentTerms like Set, but terms are separated by the first level separator of the given
+// ParserConfig and compensation is parsed using its Locale.
+func (t *PaymentTerms) SetWith(config *ParserConfig, value string) error {
+	terms := PaymentTerms{}
+	for _, term := range config.Separators.FirstLevelSplit().Split(value, -1) {
+		term, err := globals.Unquote(term)
+		if err != nil {
+			return err
+		}
+		term = strings.Join(strings.Fields(term), " ")
+		if term == "" {
+			return errors.New(fmt.Sprintf("\"%s\" contains empty payment terms", value))
+		}
+
+		if match := termsInterestRegex.FindStringSubmatch(term); match != nil {
+			rate, err := parsePercent(match[1])
+			if err != nil {
+				return errors.New(fmt.Sprintf("\"%s\" is not a valid interest rate, interest rates are given as an annual percentage (e.g. interest 8%%)", term))
+			}
+			terms.InterestRate = rate
+			continue
+		}
+
+		if match := termsCompensationRegex.FindStringSubmatch(term); match != nil {
+			if strings.ToLower(match[1]) == "statutory" {
+				terms.StatutoryCompensation = true
+				continue
+			}
+			amount, err := ParseMoneyLocale(match[1], config.locale())
+			if err != nil || amount.IsNegative() {
+				return errors.New(fmt.Sprintf("\"%s\" is not a valid compensation, compensation is given as an amount (e.g. compensation GBP 40) or \"statutory\"", term))
+			}
+			terms.Compensation = amount
+			continue
+		}
+
+		match := termsNetRegex.FindStringSubmatch(term)
+		if match == nil {
+			return errors.New(fmt.Sprintf("\"%s\" are not valid payment terms, payment terms are given as \"<discount>/<days> net <days>\", \"interest <percent>\" or \"compensation <amount>\"", term))
+		}
+		if match[1] != "" {
+			percent, err := parsePercent(match[1])
+			if err != nil || percent.Cmp(big.NewRat(100, 1)) > 0 {
+				return errors.New(fmt.Sprintf("\"%s\" is not a valid early payment discount, percentages must be between 0%% and 100%%", term))
+			}
+			days, _ := strconv.ParseUint(match[2], 10, 32)
+			terms.DiscountPercent = percent
+			terms.DiscountDays = uint(days)
+		}
+		if match[3] != "" {
+			days, _ := strconv.ParseUint(match[3], 10, 32)
+			terms.NetDays = uint(days)
+		}
+	}
+
+	if terms.HasDiscount() && terms.NetDays > 0 && terms.DiscountDays > terms.NetDays {
+		return errors.New(fmt.Sprintf("\"%s\" are not valid payment terms, the early payment discount cannot last longer than the %d days until payment is due", value, terms.NetDays))
+	}
+	*t = terms
+	return nil
+}
+
+// parsePercent parses a non-negative percentage which can be followed by a percent sign.
+func parsePercent(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	percent, ok := new(big.Rat).SetString(value)
+	if !ok || percent.Sign() < 0 {
+		return nil, errors.New(fmt.Sprintf("\"%s\" is not a valid percentage", value))
+	}
+	return percent, nil
+}
+
+// ratPercent returns the given percentage as a string (e.g. "13.25%").
+func ratPercent(percent *big.Rat) string {
+	rate := &TaxRate{Percent: percent}
+	return rate.String()
+}
@@ -15,15 +15,40 @@ import (
 )
 
 func main() {
+	// The separators can be set for the whole environment, which are overridden by -separators
+	if separators, ok := os.LookupEnv(globals.SeparatorsEnv); ok {
+		if err := api.DefaultParserConfig.Separators.Set(separators); err != nil {
+			globals.ParseErrUser.Handle(errors.New(fmt.Sprintf("%s: %s", globals.SeparatorsEnv, err.Error())))
+		}
+	}
+
 	// We change flag.Usage to be a bit more explanatory on the types
 	flag.Usage = func() {
 		flag.PrintDefaults()
+		// The separators that are active are printed, which can be changed by -separators
+		separators := api.DefaultParserConfig.Separators
 		fmt.Printf(`
 Custom types:
 
+Separators:
+	The types below are made up of lists and key-value pairs, which are separated by the following separators. These
+	can be changed using -separators or the %[5]s environment variable (e.g. "; | / =" when decimals are
+	written with commas):
+		- First level: "%[1]s"
+		- Second level: "%[2]s"
+		- Third level: "%[3]s"
+		- Key-value: "%[4]s"
+
+Quoting:
+	Any value in the types below can be wrapped in double quotes so that it can contain the separators. Separators and
+	quotes can also be escaped with a backslash:
+		company%[4]s "Smith%[1]s Jones & Co"
+		address%[4]s "1 Smith Street%[2]s Flat 2"%[2]sUK
+		description%[4]s Said \"hi\"\%[1]s twice
+
 contact:
-	Comma-seperated key-value pairs (seperated by "%s"):
-		<key>: <value>, <key>: <value>, ...
+	"%[1]s" seperated key-value pairs (seperated by "%[4]s"):
+		<key>%[4]s <value>%[1]s <key>%[4]s <value>%[1]s ...
 
 	Possible keys (string literals in parenthesis denote key possibilities):
 		- Company ("company", "comp", "c"): The name of the contact's company. (defaults to "FirstName + LastName")
@@ -31,51 +56,198 @@ contact:
 		- LastName ("lastname", "last", "l"): The last name of the contact. (required)
 		- Email ("email", "e"): The email of the contact. (required and validated)
 		- PhoneNo ("phoneno", "phone", "p"): The phone number of the contact. (required and validated)
-		- Address ("address", "addr", "a"): The address of the of the contact. This is given as a "%s" seperated list. (required) 
+		- Address ("address", "addr", "a"): The address of the of the contact. This is given as a "%[2]s" seperated list. (required) 
+		- VATID ("vatid", "vat", "vatno", "v"): The VAT identification number of the contact, including its 2 letter country
+		  prefix (e.g. "DE123456789"). (required for reverse charge and intra-community supplies, otherwise optional)
 
 items:
-	Comma-seperated list of items where each item is a list of "%s" seperated key-value pairs (seperated by "%s"):
-		<item>, <item>, ...
+	"%[1]s" seperated list of items where each item is a list of "%[2]s" seperated key-value pairs (seperated by "%[4]s"):
+		<item>%[1]s <item>%[1]s ...
 	Where <item> is:
-		<key>: <value>%s <key>: <value>%s ...
+		<key>%[4]s <value>%[2]s <key>%[4]s <value>%[2]s ...
 
 	Possible keys (string literals in parenthesis denote key possibilities):
 		- Description ("description", "desc", "d"): The description of the invoice item. (required)
-		- HoursQuantity ("hoursquantity", "hours", "h"): The hours/quantity of the invoice item. (defaults to 1)
+		- HoursQuantity ("hoursquantity", "hours", "hrs", "h", "quantity", "qty", "q"): The hours/quantity of the invoice 
+		  item. This can be a decimal with up to the number of decimal places given by -precision (e.g. "7.5"). 
+		  (defaults to 1)
+			- Hours can also be given as a duration, which is rounded as given by -time-rounding and converted into
+			  decimal hours. The unit of the item defaults to hours, and cannot be any other unit:
+				"1h30m"
+				"01:45"
+		- Unit ("unit", "uom", "u"): The unit of measure of the hours/quantity. This is either a name or a UN/ECE 
+		  Recommendation 20 code. If all items share the same unit then it is used as the header of the quantity 
+		  column, otherwise it is shown next to each quantity. (optional)
+				"hours" ("HUR"), "minutes" ("MIN"), "days" ("DAY"), "weeks" ("WEE"), "months" ("MON"), 
+				"years" ("ANN"), "pcs" ("H87"), "units" ("C62"), "kg" ("KGM"), "g" ("GRM"), "m" ("MTR"), 
+				"km" ("KMT"), "l" ("LTR")
 		- Rate ("rate", "r"), see money type: The rate charged for the invoice item. (required)
-			- The currency is determined by the 3 letter currency abbreviation (e.g. USD/GBP) or symbol before the number:
+			- The currency is determined by the 3 letter ISO 4217 currency abbreviation (e.g. USD/GBP/EUR) or symbol before the number:
 				"GBP 10.00"
 				"USD10.00"
 				"£10.00"
-		- Tax ("tax", "t"), see money type: The tax to be applied on top of the invoice item. (defaults to 0.00)
+		- Discount ("discount", "disc"): The discount taken off the rate multiplied by the hours/quantity of the invoice 
+		  item before tax. This is either a percentage or an amount in the currency of the rate:
+				"10%%"
+				"GBP 5.00"
+		- Tax ("tax", "t"): The taxes to be applied on top of the invoice item. The tax is calculated from the rate 
+		  multiplied by the hours/quantity, minus any discount. (defaults to 0%%)
+			- The tax rate is either a percentage or one of the named rates "standard" (20%%), "reduced" (5%%) or "zero":
+				"20%%"
+				"7.7%%"
+				"standard"
+			- Multiple taxes can be given as a "%[3]s" seperated list. Each tax can be given a name before its rate and a 
+			  jurisdiction in parenthesis after it. Taxes followed by "compound" are charged on the net amount plus the 
+			  taxes before them. The total of each named tax is shown on the invoice:
+				"GST 5%% (Canada) %[3]s QST 9.975%% (Quebec)"
+				"GST 5%% %[3]s QST 7.5%% compound"
+		- TaxInclusive ("taxinclusive", "inclusive", "incl", "gross"): Whether the rate of the invoice item already 
+		  includes tax, in which case the net amount and tax are calculated backwards from it. (defaults to false)
+		- Category ("category", "cat"): The UNCL5305 tax category code of the invoice item. The legal wording required for
+		  each category is printed on the invoice. (defaults to "S" if taxed, otherwise "Z")
+				"S" ("standard"): Standard rated, the tax rate must be above 0%%
+				"Z" ("zero"): Zero rated
+				"E" ("exempt"): Exempt from tax, an exemption reason must be given
+				"AE" ("reverse-charge"): Reverse charge, both contacts must have a VAT ID
+				"K" ("intra-community"): Intra-community supply, both contacts must have a VAT ID
+				"G" ("export"): Export outside the EU
+				"O" ("out-of-scope"): Outside the scope of tax
+		- Exemption ("exemption", "reason", "vatex"): The reason or VATEX code for why the invoice item is exempt from 
+		  tax. (required for exempt items)
+		- SKU ("sku", "code", "itemcode"): The seller's code for the invoice item. (optional)
+		- BuyerRef ("buyerref", "buyerreference", "po", "poline", "ref"): The buyer's reference for the invoice item, such 
+		  as the line of their purchase order. (optional)
+		- Period ("period", "serviceperiod", "service"): The period the invoice item was supplied over, given as a start
+		  and end date (see date type) separated by "-" or "to". (optional)
+				"1/12/2021-31/12/2021"
+				"1/12/2021 to 31/12/2021"
+		- Note ("note", "notes", "comment"): Any extra information about the invoice item. (optional)
+		The SKU, BuyerRef, Period and Note of each invoice item are shown on a line under its description.
+		- Group ("group", "section", "phase"): The name of the section the invoice item is shown under (e.g. "Discovery").
+		  Each section has a heading and a subtotal, and items without a section are shown under "Other". (optional)
 
 money:
-	Money string used in items. The currency is determined by the 3 letter currency abbreviation (e.g. USD/GBP) or 
-	symbol before the number. Here are some examples:
+	Money string used in items. The currency is determined by the 3 letter ISO 4217 currency abbreviation (e.g. 
+	USD/GBP/EUR) or symbol before the number. The number of decimal places depends on the currency (e.g. JPY has none
+	and KWD has 3). Here are some examples:
 		"GBP 10.00"
 		"USD10.00"
 		"£10.00"
+		"JPY 1500"
+		"KWD 1.250"
+	Negative amounts (e.g. for discounts, refunds or credit notes) can be given with a minus sign before the currency or 
+	the number, or by wrapping the amount in parentheses:
+		"-GBP 10.00"
+		"GBP -10.00"
+		"(GBP 10.00)"
+	The currency can also be given after the number and the digits can be grouped in 3s using commas, periods or 
+	spaces (whichever is not the decimal separator of the locale). Giving more decimal places than the currency has is
+	an error:
+		"1,234.50 EUR"
+		"10 €"
+		"USD 1 000"
 
 bank:
-	Comma-seperated key-value pairs (seperated by "%s"):
-		<key>: <value>, <key>: <value>, ...
+	"%[1]s" seperated key-value pairs (seperated by "%[4]s"):
+		<key>%[4]s <value>%[1]s <key>%[4]s <value>%[1]s ...
 
 	Possible keys (string literals in parenthesis denote key possibilities):
 		- Bank ("bank", "b"): The name of the bank. (required)
 		- Account No. ("accountno", "account", "a/c", "a"): The account number. (required)
 		- Sort Code ("sortcode", "sort", "code", "s"): The sort code. (required)
 
+locale:
+	The name of a locale which determines the decimal and grouping separators of money, as well as where the currency
+	is placed. One of:
+		- "default": GBP 1234.56
+		- "en-GB"/"en-US": £1,234.56
+		- "de-DE"/"fr-FR"/"es-ES"/"it-IT": 1.234,56 €, 1 234,56 €
+		- "de-AT"/"nl-NL": € 1.234,56
+		- "de-CH"/"fr-CH": CHF 1'234.56, 1 234,56 CHF
+	Money is parsed using the locale's separators, so the locale must be given before any flags containing money (e.g.
+	-locale de-DE -items "d%[4]s Did thing%[2]s r%[4]s 1.234 €").
+
+currency:
+	A 3 letter ISO 4217 currency abbreviation (e.g. USD/GBP/EUR) or symbol.
+
+rates:
+	A file of exchange rates in one of the following formats:
+		- ECB euro foreign exchange reference rates XML (e.g. eurofxref-hist.xml). All rates are from EUR and cross
+		  rates are calculated through EUR.
+		- CSV where each record is: <date>,<from>,<to>,<rate>. Dates are in YYYY-MM-DD or D/M/YYYY format and a header
+		  record is optional:
+			date,from,to,rate
+			2021-12-01,USD,GBP,0.75
+
+terms:
+	"%[1]s" seperated payment terms. Any of the following can be given:
+		- "<discount>/<days> net <days>": A percentage discount given if the invoice is paid within the first number of
+		  days, and the number of days after the invoice date that the invoice is due. Either part can be left out:
+			"2/10 net 30"
+			"net 14"
+		- "interest <percent>": The annual percentage of simple interest charged for each day that the invoice is paid
+		  late (e.g. 8%% plus the Bank of England base rate under the Late Payment of Commercial Debts Act):
+			"interest 13.25%%"
+		- "compensation <amount>": The fixed compensation charged if the invoice is paid late. This can be "statutory"
+		  for the fixed sum set out in the Late Payment of Commercial Debts (Interest) Act 1998 (GBP 40.00, 70.00 or
+		  100.00 depending on the amount owed):
+			"compensation GBP 40"
+			"compensation statutory"
+
+time-rounding:
+	The increment that durations are rounded to as a Go-style duration (e.g. "6m" or "15m"), followed by an optional
+	rounding mode (see rounding type). Durations are rounded to the nearest increment by default and "0" turns off
+	rounding:
+		"6m"
+		"15m up"
+
+rounding:
+	How amounts are rounded to the currency's minor unit. One of:
+		- "half-up": round to the nearest minor unit, halves are rounded away from zero.
+		- "half-even" ("bankers"): round to the nearest minor unit, halves are rounded to the nearest even minor unit.
+		- "half-down": round to the nearest minor unit, halves are rounded towards zero.
+		- "down" ("truncate"): truncate towards zero.
+		- "up": round away from zero.
+
+currency-rounding:
+	"%[1]s" seperated rounding modes for amounts in each currency, each given as a currency abbreviation or symbol
+	followed by a rounding mode (see rounding type):
+		"CHF half-even%[1]s JPY down"
+
 date:
-	Date in D/M/YYYY format (sorry Americans).
-`, globals.KeyValueSep,
-   globals.SecondLevelSep,
-   globals.SecondLevelSep,
-   globals.KeyValueSep,
-   globals.SecondLevelSep,
-   globals.SecondLevelSep,
-   globals.KeyValueSep)
+	Date in D/M/YYYY format (sorry Americans). Dates can also be given in YYYY-MM-DD format.
+
+document:
+	A JSON, YAML or TOML invoice document given by -input. Its keys are the names of the flags above (e.g. "from",
+	"items", "due", "time-rounding") and each value can be given in the same format as its flag. Contacts, the bank and
+	each item can also be given as a table of their key-value pairs, and lists (such as addresses, taxes and deductions)
+	can be given as lists instead of seperated strings:
+		number: 1
+		date: 2021-12-01
+		terms: net 30
+		from:
+		  first: John
+		  last: Smith
+		  email: johnsmith@example.com
+		  phone: "01234567890"
+		  address: [1 Smith Street, Smith Town, UK]
+		to: "f%[4]s Jane%[1]s l%[4]s Doe%[1]s e%[4]s janedoe@example.com%[1]s p%[4]s 01234567890%[1]s a%[4]s 1 Doe Street%[2]sUK"
+		items:
+		  - description: Did thing
+		    hours: 1h30m
+		    rate: GBP 60.00
+		    tax: [GST 5%%, QST 9.975%% compound]
+	The locale, precision and time-rounding of the document are used when parsing the rest of it.
+`, separators.FirstLevel,
+   separators.SecondLevel,
+   separators.ThirdLevel,
+   separators.KeyValue,
+   globals.SeparatorsEnv)
 	}
 
+	// Separators
+	flag.Var(&api.DefaultParserConfig.Separators, "separators", fmt.Sprintf("The `separators` of the custom types below, given as the first level, second level, third level and key-value separators separated by spaces: \"; | / =\". This must be given before any flags that use them. (optional, can also be given by the %s environment variable)", globals.SeparatorsEnv))
+
 	// Verbose
 	verbosePtr := flag.Bool("verbose", false, "Whether or not to print some extra info.")
 
@@ -100,9 +272,52 @@ date:
 	flag.Var(&invoiceDate, "date", "The `date` the invoice was created.")
 	flag.Var(&dueDate, "due", "The `date` on which the invoice needs to be paid.")
 
+	// Quantity precision
+	flag.UintVar(&api.QuantityPrecision, "precision", api.QuantityPrecision, "The maximum number of decimal places of the hours/quantity of each item. This must be given before -items.")
+
+	// Time rounding
+	flag.Var(api.DefaultTimeRounding, "time-rounding", "The `rounding` of hours given as durations, which is noted on the invoice: \"6m\" or \"15m up\". This must be given before -items.")
+
 	// Invoice items
 	items := make(api.Items, 0)
-	flag.Var(&items, "items", "The `items` that the employee performed and needs to be paid for. (required, hrs/qty defaults to 1, tax defaults to 0)")
+	flag.Var(&items, "items", "The `items` that the employee performed and needs to be paid for. (required, hrs/qty defaults to 1, tax defaults to 0%)")
+
+	// Tax-inclusive pricing
+	taxInclusivePtr := flag.Bool("inclusive", false, "Whether the rates of all items include tax, in which case the net amount and tax of each item are calculated backwards from its rate. (optional, items can also be marked as tax-inclusive individually)")
+
+	// Invoice-level discounts and surcharges
+	discount := api.Discount{}
+	flag.Var(&discount, "discount", "The `discount` taken off all items before tax, given as a percentage or an amount in the currency of the invoice: \"10%\" or \"GBP 50.00\". Fixed amounts are split between the items in proportion to their subtotals. (optional)")
+	surcharge := api.Discount{Surcharge: true}
+	flag.Var(&surcharge, "surcharge", "The `surcharge` added to all items before tax, given in the same way as -discount. This cannot be given with -discount. (optional)")
+
+	// Withholding
+	deductions := make(api.Deductions, 0)
+	flag.Var(&deductions, "deductions", "First level seperated `deductions` withheld from the total of the invoice (e.g. IRPF or ritenuta), each given as a percentage of the net total or an amount, with an optional name: \"IRPF 15%\" or \"Retention EUR 50.00\". The amount payable is shown separately from the total. (optional)")
+
+	// Payment terms
+	terms := api.PaymentTerms{}
+	flag.Var(&terms, "terms", "The payment `terms` of the invoice, such as an early payment discount and interest on late payments: \"2/10 net 30, interest 13.25%, compensation statutory\". (optional, the due date defaults to the net days after the invoice date if given)")
+
+	// Rounding mode
+	rounding := api.RoundDefault
+	flag.Var(&rounding, "rounding", "The `rounding` mode used when calculating item subtotals. (defaults to the currency's rounding mode given by -currency-rounding, which is half-up if not given)")
+	currencyRounding := make(api.CurrencyRoundings)
+	flag.Var(&currencyRounding, "currency-rounding", "First level separated `roundings` of amounts in each currency, each given as a currency followed by a rounding mode: \"CHF half-even, JPY down\". These are used when -rounding is not given. (optional)")
+
+	// Currency conversion
+	currency := api.ZeroCurrency
+	flag.Var(&currency, "currency", "The `currency` the invoice is issued in. Items in other currencies are converted using the exchange rates given by -rates. (defaults to the currency of the items)")
+	rates := api.RateFile{}
+	flag.Var(&rates, "rates", "The `path` to a file of exchange rates, either an ECB reference rates XML file or a CSV file of date,from,to,rate records. The latest rate on or before the invoice date is used. (optional, requires -currency)")
+
+	// Locale
+	flag.Var(api.DefaultParserConfig.Locale, "locale", "The `locale` used to parse and format money. This must be given before any flags containing money.")
+
+	// Input document
+	inputPathPtr := flag.String("input", "", "The `path` to a JSON, YAML or TOML invoice document, or \"-\" to read it from stdin. Flags that are also given override the fields of the document. (optional)")
+	format := api.DocumentFormat("")
+	flag.Var(&format, "format", "The `format` of the -input document: json, yaml or toml. (defaults to the extension of the document, or is detected from its contents)")
 
 	// Output file
 	outputPathPtr := flag.String("output", "invoice.pdf", "The output filepath for the invoice.")
@@ -110,10 +325,119 @@ date:
 	// Parse
 	flag.Parse()
 
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	// Fill in the fields that were not given as flags from the input document
+	var locale *api.Locale
+	if given["locale"] {
+		locale = api.DefaultParserConfig.Locale
+	}
+	dueGiven := given["due"]
+	if *inputPathPtr != "" {
+		document, err := readDocument(*inputPathPtr, format)
+		if err != nil {
+			globals.ParseErrUser.Handle(err)
+		}
+		if !given["number"] && document.Number != 0 {
+			*numberPtr = document.Number
+		}
+		if !given["from"] && document.From != nil {
+			from = *document.From
+		}
+		if !given["to"] && document.To != nil {
+			to = *document.To
+		}
+		if !given["bank"] && document.Bank != nil {
+			bank = *document.Bank
+		}
+		if !given["date"] && document.InvoiceDate != nil {
+			invoiceDate = *document.InvoiceDate
+		}
+		if !given["due"] && document.DueDate != nil {
+			dueDate = *document.DueDate
+			dueGiven = true
+		}
+		if !given["items"] && document.Items != nil {
+			items = *document.Items
+		}
+		if !given["inclusive"] && document.TaxInclusive {
+			*taxInclusivePtr = true
+		}
+		if !given["discount"] && !given["surcharge"] && document.Discount != nil {
+			if document.Discount.Surcharge {
+				surcharge = *document.Discount
+			} else {
+				discount = *document.Discount
+			}
+		}
+		if !given["deductions"] && len(document.Deductions) > 0 {
+			deductions = document.Deductions
+		}
+		if !given["terms"] && document.Terms != nil {
+			terms = *document.Terms
+		}
+		if !given["rounding"] {
+			rounding = document.Rounding
+		}
+		if !given["currency-rounding"] && document.CurrencyRounding != nil {
+			currencyRounding = document.CurrencyRounding
+		}
+		if !given["currency"] && document.Currency != api.ZeroCurrency {
+			currency = document.Currency
+		}
+		if documentRates, ok := document.Rates.(*api.RateFile); !given["rates"] && ok {
+			rates = *documentRates
+		}
+		if !given["locale"] {
+			locale = document.Locale
+		}
+	}
+
+	// The due date defaults to the net days of the payment terms after the invoice date
+	if !dueGiven && terms.NetDays > 0 {
+		dueDate = *invoiceDate.AddDays(int(terms.NetDays))
+	}
+
 	// Construct the invoice value to check required flags
-	invoice, err := api.NewInvoice(*numberPtr, &from, &to, &items, &bank, &invoiceDate, &dueDate)
+	options := []api.InvoiceOption{api.WithRounding(rounding)}
+	for abbr, mode := range currencyRounding {
+		options = append(options, api.WithCurrencyRounding(*api.CurrencyFromAbbr(abbr), mode))
+	}
+	if currency != api.ZeroCurrency {
+		options = append(options, api.WithConversion(currency, &rates))
+	} else if rates.Path != "" {
+		globals.InvalidInvoice.Handle(errors.New("-rates can only be given with -currency, as the exchange rates are only used to convert items into the currency of the invoice"))
+	}
+	if locale != nil {
+		options = append(options, api.WithLocale(locale))
+	}
+	if *taxInclusivePtr {
+		options = append(options, api.WithTaxInclusive())
+	}
+	if len(deductions) > 0 {
+		options = append(options, api.WithDeductions(deductions...))
+	}
+	switch {
+	case !discount.IsZero() && !surcharge.IsZero():
+		globals.InvalidInvoice.Handle(errors.New("only one of -discount and -surcharge can be given"))
+	case !discount.IsZero():
+		options = append(options, api.WithDiscount(&discount))
+	case !surcharge.IsZero():
+		options = append(options, api.WithDiscount(&surcharge))
+	}
+	if !terms.IsZero() {
+		options = append(options, api.WithPaymentTerms(&terms))
+	}
+	invoice, err := api.NewInvoice(*numberPtr, &from, &to, &items, &bank, &invoiceDate, &dueDate, options...)
 	if err != nil {
-		globals.RequiredFlag.Handle(err)
+		var requiredErr api.RequiredFieldsError
+		if errors.As(err, &requiredErr) {
+			globals.RequiredFlag.Handle(err)
+		}
+		globals.InvalidInvoice.Handle(err)
 	}
 
 	// Print out the parsed information if verbose is given
@@ -155,3 +479,20 @@ date:
 		globals.FileErr.Handle(err)
 	}
 }
+
+// readDocument decodes the invoice document at the given path, or stdin if the path is "-". If no format is given then
+// the extension of the path is used, falling back To detecting the format From the contents of the document.
+func readDocument(path string, format api.DocumentFormat) (*api.Invoice, error) {
+	if path == "-" {
+		return api.DecodeInvoice(os.Stdin, format)
+	}
+	if format == "" {
+		format, _ = api.DocumentFormatFromPath(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("cannot open invoice document: \"%s\"", path))
+	}
+	defer f.Close()
+	return api.DecodeInvoice(f, format)
+}
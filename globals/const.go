@@ -1,25 +1,12 @@
 package globals
 
-import (
-	"fmt"
-	"regexp"
-)
-
+// The default separators of each level of the flag grammar.
 const (
 	FirstLevelSep  = ","
 	SecondLevelSep = ";"
 	ThirdLevelSep  = "|"
 	KeyValueSep    = ":"
-	SplitFormat    = "%s ?"
 )
 
-var (
-	// FirstLevelSplit (const)
-	FirstLevelSplit  = regexp.MustCompile(fmt.Sprintf(SplitFormat, FirstLevelSep))
-	// SecondLevelSplit (const)
-	SecondLevelSplit = regexp.MustCompile(fmt.Sprintf(SplitFormat, SecondLevelSep))
-	// ThirdLevelSplit (const)
-	ThirdLevelSplit  = regexp.MustCompile(fmt.Sprintf(SplitFormat, ThirdLevelSep))
-	// KeyValueSplit (const)
-	KeyValueSplit    = regexp.MustCompile(fmt.Sprintf(SplitFormat, KeyValueSep))
-)
+// SeparatorsEnv is the environment variable that the Separators can be given in, in the same format as Separators.Set.
+const SeparatorsEnv = "GINVOICE_SEPARATORS"
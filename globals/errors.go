@@ -24,6 +24,7 @@ var (
 	FileErrUser          = CliError{3, false, "A user file error occurred"}
 	FileErr              = CliError{4, true, "A file error occurred"}
 	InvoiceGenerationErr = CliError{5, true, "Error when generating invoice"}
+	InvalidInvoice       = CliError{6, false, "The invoice is invalid"}
 )
 
 // Handle the print of the error details as well as exiting with the defined exit code.
@@ -0,0 +1,109 @@
+package globals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Separators are the separators of each level of the flag grammar. Lists of key-value pairs are separated by the
+// FirstLevel separator (e.g. a Contact), lists within them by the SecondLevel separator (e.g. an address) and so on.
+type Separators struct {
+	FirstLevel  string
+	SecondLevel string
+	ThirdLevel  string
+	KeyValue    string
+}
+
+// DefaultSeparators returns the Separators that are used if none are given.
+func DefaultSeparators() Separators {
+	return Separators{
+		FirstLevel:  FirstLevelSep,
+		SecondLevel: SecondLevelSep,
+		ThirdLevel:  ThirdLevelSep,
+		KeyValue:    KeyValueSep,
+	}
+}
+
+// FirstLevelSplit returns the Splitter for the FirstLevel separator.
+func (s *Separators) FirstLevelSplit() *Splitter {
+	return &Splitter{s.FirstLevel}
+}
+
+// SecondLevelSplit returns the Splitter for the SecondLevel separator.
+func (s *Separators) SecondLevelSplit() *Splitter {
+	return &Splitter{s.SecondLevel}
+}
+
+// ThirdLevelSplit returns the Splitter for the ThirdLevel separator.
+func (s *Separators) ThirdLevelSplit() *Splitter {
+	return &Splitter{s.ThirdLevel}
+}
+
+// KeyValueSplit returns the Splitter for the KeyValue separator.
+func (s *Separators) KeyValueSplit() *Splitter {
+	return &Splitter{s.KeyValue}
+}
+
+// all returns each of the Separators in order of their level, with the KeyValue separator last.
+func (s *Separators) all() []string {
+	return []string{s.FirstLevel, s.SecondLevel, s.ThirdLevel, s.KeyValue}
+}
+
+// Quote wraps the value in Quote(s) if it would otherwise be changed by splitting or Unquote, so that it is parsed
+// back as is. This is the case if it is empty, contains one of the Separators, a Quote or an Escape, or starts or ends
+// with whitespace.
+func (s *Separators) Quote(value string) string {
+	quote := value == "" || strings.TrimSpace(value) != value || strings.ContainsAny(value, string(Quote) + string(Escape))
+	for _, sep := range s.all() {
+		quote = quote || strings.Contains(value, sep)
+	}
+	if !quote {
+		return value
+	}
+	return string(Quote) + EscapeQuotes(value) + string(Quote)
+}
+
+// Validate checks that none of the Separators are empty, contain whitespace, a Quote or an Escape, or contain one of
+// the other Separators.
+func (s *Separators) Validate() error {
+	seps := s.all()
+	for i, sep := range seps {
+		if sep == "" || strings.ContainsAny(sep, " \t\n" + string(Quote) + string(Escape)) {
+			return errors.New(fmt.Sprintf("\"%s\" is not a valid separator, separators cannot be empty or contain whitespace, '%c' or '%c'", sep, Quote, Escape))
+		}
+		for j, other := range seps {
+			if i != j && strings.Contains(sep, other) {
+				return errors.New(fmt.Sprintf("\"%s\" is not a valid separator as it contains the separator \"%s\", each separator must be different", sep, other))
+			}
+		}
+	}
+	return nil
+}
+
+// String returns the Separators in the same format that they are parsed From (e.g. ", ; | :").
+func (s *Separators) String() string {
+	return strings.Join(s.all(), " ")
+}
+
+// Set the Separators From the whitespace-separated FirstLevel, SecondLevel, ThirdLevel and KeyValue separators.
+//
+// A valid string value can be:
+//  ; | / =
+func (s *Separators) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 4 {
+		return errors.New(fmt.Sprintf("\"%s\" are not valid separators, separators are given as the first level, second level, third level and key-value separators separated by spaces (e.g. \"; | / =\")", value))
+	}
+	separators := Separators{
+		FirstLevel:  fields[0],
+		SecondLevel: fields[1],
+		ThirdLevel:  fields[2],
+		KeyValue:    fields[3],
+	}
+	if err := separators.Validate(); err != nil {
+		return err
+	}
+	*s = separators
+	return nil
+}
@@ -0,0 +1,91 @@
+package globals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Quote is the character that a value can be wrapped in so that any separators within it are not split on.
+	Quote  = '"'
+	// Escape is the character that a separator, Quote or Escape can be prefixed with so that it is not split on.
+	Escape = '\\'
+)
+
+// Splitter splits a value around each of its separators that is not quoted or escaped. The Quote and Escape
+// characters are kept in each part, so that the parts can be split again at a lower level, and are only removed by
+// Unquote once the value can no longer be split.
+type Splitter struct {
+	Sep string
+}
+
+// Split slices the value into the parts between each separator that is not quoted or escaped, along with a single
+// space after each separator. If n is positive then at most n parts are returned, the last being the unsplit
+// remainder, and if n is negative then all the parts are returned. This behaves like regexp.Regexp.Split with the
+// "<sep> ?" regex.
+func (s *Splitter) Split(value string, n int) []string {
+	parts, _ := s.SplitOffsets(value, n)
+	return parts
+}
+
+// SplitOffsets is the same as Split but also returns the byte offset of each part within the value.
+func (s *Splitter) SplitOffsets(value string, n int) ([]string, []int) {
+	if n == 0 {
+		return nil, nil
+	}
+	parts, offsets := make([]string, 0), make([]int, 0)
+	quoted, escaped := false, false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case value[i] == Escape:
+			escaped = true
+		case value[i] == Quote:
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(value[i:], s.Sep) && (n < 0 || len(parts) < n - 1):
+			parts, offsets = append(parts, value[start:i]), append(offsets, start)
+			i += len(s.Sep)
+			if i < len(value) && value[i] == ' ' {
+				i++
+			}
+			start = i
+			i--
+		}
+	}
+	return append(parts, value[start:]), append(offsets, start)
+}
+
+// Unquote removes the Quote(s) and Escape(s) From a value once it has been split. An error is returned if a Quote is
+// not closed or if the value ends with an Escape.
+func Unquote(value string) (string, error) {
+	var b strings.Builder
+	quoted, escaped := false, false
+	for _, r := range value {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == Escape:
+			escaped = true
+		case r == Quote:
+			quoted = !quoted
+		default:
+			b.WriteRune(r)
+		}
+	}
+	switch {
+	case quoted:
+		return "", errors.New(fmt.Sprintf("\"%s\" has an unclosed quote, quotes within values must be escaped with a '%c'", value, Escape))
+	case escaped:
+		return "", errors.New(fmt.Sprintf("\"%s\" ends with a '%c', which must be followed by the character it escapes", value, Escape))
+	}
+	return b.String(), nil
+}
+
+// EscapeQuotes prefixes the Quote(s) and Escape(s) in the value with an Escape, so that it is unchanged by Unquote.
+func EscapeQuotes(value string) string {
+	return strings.NewReplacer(string(Escape), string(Escape) + string(Escape), string(Quote), string(Escape) + string(Quote)).Replace(value)
+}
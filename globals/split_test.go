@@ -0,0 +1,106 @@
+package globals
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitter(t *testing.T) {
+	separators := DefaultSeparators()
+	for _, test := range []struct{
+		splitter *Splitter
+		input    string
+		n        int
+		out      []string
+	}{
+		{separators.FirstLevelSplit(), "a, b,c", -1, []string{"a", "b", "c"}},
+		{separators.FirstLevelSplit(), "a,  b", -1, []string{"a", " b"}},
+		{separators.FirstLevelSplit(), "", -1, []string{""}},
+		{separators.FirstLevelSplit(), "a,b", 0, nil},
+		{separators.FirstLevelSplit(), `company: "Smith, Jones", f: John`, -1, []string{`company: "Smith, Jones"`, "f: John"}},
+		{separators.FirstLevelSplit(), `a\, b, c`, -1, []string{`a\, b`, "c"}},
+		{separators.FirstLevelSplit(), `"a \" , b", c`, -1, []string{`"a \" , b"`, "c"}},
+		{separators.KeyValueSplit(), `d: "a: b": c`, 2, []string{"d", `"a: b": c`}},
+		{separators.KeyValueSplit(), "d:a:b", 2, []string{"d", "a:b"}},
+		{&Splitter{"::"}, "a::b:c:: d", -1, []string{"a", "b:c", "d"}},
+	} {
+		if out := test.splitter.Split(test.input, test.n); !reflect.DeepEqual(out, test.out) {
+			t.Errorf("splitting \"%s\" on \"%s\" gives %q, expected %q", test.input, test.splitter.Sep, out, test.out)
+		}
+		parts, offsets := test.splitter.SplitOffsets(test.input, test.n)
+		for i, part := range parts {
+			if offsets[i] + len(part) > len(test.input) || test.input[offsets[i]:offsets[i] + len(part)] != part {
+				t.Errorf("splitting \"%s\" on \"%s\" gives part %q at the incorrect offset %d", test.input, test.splitter.Sep, part, offsets[i])
+			}
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	separators := DefaultSeparators()
+	for _, test := range []struct{
+		input string
+		err   string
+		out   string
+	}{
+		{input: "plain", out: "plain"},
+		{input: `"Smith, Jones"`, out: "Smith, Jones"},
+		{input: `Smith\, Jones`, out: "Smith, Jones"},
+		{input: `"say \"hi\""`, out: `say "hi"`},
+		{input: `a\\b`, out: `a\b`},
+		{input: `""`, out: ""},
+		{input: `"unclosed`, err: "has an unclosed quote"},
+		{input: `trailing\`, err: "ends with a '\\'"},
+	} {
+		out, err := Unquote(test.input)
+		switch {
+		case err != nil && test.err == "":
+			t.Errorf("unquoting \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		case err == nil && test.err != "":
+			t.Errorf("unquoting \"%s\" does not return the expected error: \"%s\"", test.input, test.err)
+		case err != nil && !strings.Contains(err.Error(), test.err):
+			t.Errorf("unquoting \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err, err.Error())
+		case err == nil && out != test.out:
+			t.Errorf("unquoting \"%s\" gives \"%s\", expected \"%s\"", test.input, out, test.out)
+		}
+		if err == nil {
+			if quoted, _ := Unquote(separators.Quote(out)); quoted != out {
+				t.Errorf("quoting \"%s\" does not unquote To itself, got \"%s\"", out, quoted)
+			}
+		}
+	}
+}
+
+func TestSeparators(t *testing.T) {
+	for _, test := range []struct{
+		input string
+		err   string
+		out   Separators
+	}{
+		{input: ", ; | :", out: DefaultSeparators()},
+		{input: "  ;  |\t/ =  ", out: Separators{";", "|", "/", "="}},
+		{input: "; | /", err: "are not valid separators"},
+		{input: "; | / = +", err: "are not valid separators"},
+		{input: "; | ; =", err: "\";\" is not a valid separator as it contains the separator \";\""},
+		{input: "; ;; / =", err: "\";;\" is not a valid separator as it contains the separator \";\""},
+		{input: "; | \" =", err: "separators cannot be empty or contain whitespace"},
+		{input: "; | \\ =", err: "separators cannot be empty or contain whitespace"},
+	} {
+		separators := DefaultSeparators()
+		err := separators.Set(test.input)
+		switch {
+		case err != nil && test.err == "":
+			t.Errorf("setting separators \"%s\" is not supposed To return error: \"%s\"", test.input, err.Error())
+		case err == nil && test.err != "":
+			t.Errorf("setting separators \"%s\" does not return the expected error: \"%s\"", test.input, test.err)
+		case err != nil && !strings.Contains(err.Error(), test.err):
+			t.Errorf("setting separators \"%s\" returns the incorrect error:\nexpected: \"%s\"\ngot: \"%s\"", test.input, test.err, err.Error())
+		case err == nil && separators != test.out:
+			t.Errorf("setting separators \"%s\" gives %v, expected %v", test.input, separators, test.out)
+		}
+		if err != nil && separators != DefaultSeparators() {
+			t.Errorf("setting invalid separators \"%s\" changes the separators To %v", test.input, separators)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 module github.com/andygello555/ginvoice
 
-go 1.13
+go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/andygello555/gotils v1.2.7
 	github.com/johnfercher/maroto v0.31.0
+	gopkg.in/yaml.v3 v3.0.1
 )